@@ -0,0 +1,179 @@
+package moneroproto
+
+import (
+	"io"
+	"reflect"
+)
+
+// Option configures an Encoder or a Decoder. Options which make sense only for one direction
+// are ignored by the other
+type Option func(*options)
+
+type options struct {
+	strict         bool
+	limits         Limits
+	noPreamble     bool
+	lenientNumbers bool
+	streams        map[string]ElementHandler
+}
+
+func newOptions(opts []Option) options {
+	o := options{limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Strict makes Decoder fail with ErrUnknownField on fields the target struct doesn't declare
+// instead of skipping them
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithLimits makes Decoder apply the given limits instead of DefaultLimits, zero fields of limits
+// keep their default values
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits.withDefaults()
+	}
+}
+
+// WithoutPreamble makes Encoder omit and Decoder not expect MessagePreamble before every message,
+// e.g. when the messages are nested into another framing
+func WithoutPreamble() Option {
+	return func(o *options) {
+		o.noPreamble = true
+	}
+}
+
+// Encoder writes messages into a stream
+type Encoder struct {
+	e    encoder
+	opts options
+}
+
+func NewEncoder(writer io.Writer, opts ...Option) *Encoder {
+	o := newOptions(opts)
+	return &Encoder{
+		e:    encoder{writer: writer},
+		opts: o,
+	}
+}
+
+// Encode writes a tagged struct or a map as a message
+func (enc *Encoder) Encode(obj interface{}) error {
+	return enc.EncodeWith(obj)
+}
+
+// EncodeWith works like Encode but takes the elements of the root struct's array fields named by
+// producers from them, see ProduceArray. Producers apply to this message only
+func (enc *Encoder) EncodeWith(obj interface{}, producers ...ArrayProducer) error {
+	enc.begin()
+	enc.e.producers = producers
+	return enc.end(enc.e.encode(obj))
+}
+
+// EncodeSection writes the section as a message
+func (enc *Encoder) EncodeSection(s *Section) error {
+	enc.begin()
+	return enc.end(enc.e.writeSection(s, 0))
+}
+
+func (enc *Encoder) begin() {
+	enc.e.path = enc.e.path[:0]
+	enc.e.buf = enc.e.buf[:0]
+	enc.e.flushed = 0
+	if !enc.opts.noPreamble {
+		enc.e.buf = append(enc.e.buf, MessagePreamble...)
+	}
+}
+
+// end writes out the rest of the message unless encoding failed
+func (enc *Encoder) end(err error) error {
+	enc.e.producers = nil
+	if err != nil {
+		enc.e.buf = enc.e.buf[:0]
+		return err
+	}
+
+	err = enc.e.flush()
+	if err != nil {
+		return enc.e.error(err, reflect.Invalid, 0)
+	}
+
+	return nil
+}
+
+// Decoder reads messages from a stream. Limits are applied to every message separately.
+// If the stream doesn't implement io.ByteReader the Decoder buffers it and may read past the end
+// of the last decoded message
+type Decoder struct {
+	d    decoder
+	opts options
+}
+
+func NewDecoder(reader io.Reader, opts ...Option) *Decoder {
+	return newDecoder(newByteReader(reader), opts)
+}
+
+// newMessageDecoder returns a Decoder which doesn't buffer the stream, so it consumes exactly
+// the bytes of the message. It's used by the functions reading a single message
+func newMessageDecoder(reader io.Reader, opts ...Option) *Decoder {
+	dec := newDecoder(nil, opts)
+	dec.d.reader = newExactReader(reader, &dec.d.scratch)
+	return dec
+}
+
+func newDecoder(reader byteReader, opts []Option) *Decoder {
+	o := newOptions(opts)
+	return &Decoder{
+		d: decoder{
+			reader:         reader,
+			strict:         o.strict,
+			lenientNumbers: o.lenientNumbers,
+			limits:         o.limits,
+			streams:        o.streams,
+		},
+		opts: o,
+	}
+}
+
+// Decode reads a message into a tagged struct or a map pointed by obj
+func (dec *Decoder) Decode(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return dec.d.error(ErrNotPointer, v.Kind(), 0)
+	}
+
+	err := dec.begin()
+	if err != nil {
+		return err
+	}
+
+	return dec.d.decodeRoot(v.Elem())
+}
+
+// DecodeSection reads a message into a section
+func (dec *Decoder) DecodeSection() (*Section, error) {
+	err := dec.begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.d.readSection()
+}
+
+func (dec *Decoder) begin() error {
+	dec.d.path = dec.d.path[:0]
+	dec.d.depth = 0
+	dec.d.allocated = 0
+	if dec.opts.noPreamble {
+		return nil
+	}
+
+	return dec.d.readPreamble()
+}
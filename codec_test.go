@@ -0,0 +1,83 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoderDecoderStream(t *testing.T) {
+	s := NewSection()
+	s.SetString("status", "OK")
+
+	buffer := bytes.Buffer{}
+	enc := NewEncoder(&buffer)
+	assert.Nil(t, enc.Encode(SimpleObject{1}))
+	assert.Nil(t, enc.EncodeSection(s))
+	assert.Nil(t, enc.Encode(&SimpleObject{2}))
+
+	dec := NewDecoder(&buffer)
+
+	var first, third SimpleObject
+	assert.Nil(t, dec.Decode(&first))
+
+	second, err := dec.DecodeSection()
+	assert.Nil(t, err)
+
+	assert.Nil(t, dec.Decode(&third))
+
+	assert.Equal(t, SimpleObject{1}, first)
+	assert.Equal(t, s, second)
+	assert.Equal(t, SimpleObject{2}, third)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestEncoderWithoutPreamble(t *testing.T) {
+	expected := bytes.Buffer{}
+	err := Encode(&expected, SimpleObject{0x1122334455667788})
+	assert.Nil(t, err)
+
+	buffer := bytes.Buffer{}
+	err = NewEncoder(&buffer, WithoutPreamble()).Encode(SimpleObject{0x1122334455667788})
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Bytes(), buffer.Bytes())
+
+	var obj SimpleObject
+	err = NewDecoder(&buffer, WithoutPreamble()).Decode(&obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{0x1122334455667788}, obj)
+}
+
+func TestDecoderStrict(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, ExtendedObject{Txs: 1})
+	assert.Nil(t, err)
+
+	var obj SimpleObject
+	err = NewDecoder(&buffer, Strict()).Decode(&obj)
+
+	assert.True(t, errors.Is(err, ErrUnknownField))
+}
+
+func TestDecoderLimitsPerMessage(t *testing.T) {
+	buffer := bytes.Buffer{}
+	enc := NewEncoder(&buffer)
+	assert.Nil(t, enc.Encode(BlockCompleteEntry{Block: []byte("block")}))
+	assert.Nil(t, enc.Encode(BlockCompleteEntry{Block: []byte("block")}))
+
+	dec := NewDecoder(&buffer, WithLimits(Limits{MaxAllocation: 8}))
+
+	var obj BlockCompleteEntry
+	assert.Nil(t, dec.Decode(&obj))
+	assert.Nil(t, dec.Decode(&obj))
+	assert.Equal(t, []byte("block"), obj.Block)
+}
+
+func TestDecoderNotPointer(t *testing.T) {
+	err := NewDecoder(bytes.NewReader(MessagePreamble)).Decode(SimpleObject{})
+
+	assert.True(t, errors.Is(err, ErrNotPointer))
+}
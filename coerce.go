@@ -0,0 +1,68 @@
+package moneroproto
+
+import (
+	"reflect"
+)
+
+// LenientNumbers makes Decoder convert between integer wire types and Go integer kinds the way epee
+// does: any integer is accepted by any integer field if the value fits, otherwise ErrValueOverflow
+// is returned. Booleans and integers 0 and 1 are interchangeable as well
+func LenientNumbers() Option {
+	return func(o *options) {
+		o.lenientNumbers = true
+	}
+}
+
+func isIntegerWireType(t byte) bool {
+	return t >= TypeInt64 && t <= TypeUint8
+}
+
+// isCoercible reports whether a value of the wire type is converted into a Go value of the kind
+// in the lenient mode
+func isCoercible(valueType byte, kind reflect.Kind) bool {
+	if isIntegerWireType(valueType) {
+		return isIntegerKind(kind) || kind == reflect.Bool
+	}
+
+	return valueType == TypeBool && isIntegerKind(kind)
+}
+
+// decodeCoerced reads an integer or a bool of the wire type into v converting it, see LenientNumbers
+func (d *decoder) decodeCoerced(valueType byte, v reflect.Value) error {
+	if valueType == TypeBool {
+		val, err := d.readBool()
+		if err != nil {
+			return err
+		}
+
+		bit := uint64(0)
+		if val {
+			bit = 1
+		}
+
+		if isSignedKind(v.Kind()) {
+			v.SetInt(int64(bit))
+		} else {
+			v.SetUint(bit)
+		}
+
+		return nil
+	}
+
+	if v.Kind() != reflect.Bool {
+		return readInteger(d, v, valueType)
+	}
+
+	var val uint64
+	err := readInteger(d, reflect.ValueOf(&val).Elem(), valueType)
+	if err != nil {
+		return err
+	}
+
+	if val > 1 {
+		return ErrValueOverflow
+	}
+
+	v.SetBool(val == 1)
+	return nil
+}
@@ -0,0 +1,81 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type CoercedObject struct {
+	Flag   bool     `monerobinkv:"flag"`
+	Count  int32    `monerobinkv:"count"`
+	Values []uint64 `monerobinkv:"values"`
+}
+
+func decodeLenient(s *Section, obj interface{}) error {
+	buffer := bytes.Buffer{}
+	err := WriteSection(&buffer, s)
+	if err != nil {
+		return err
+	}
+
+	return NewDecoder(&buffer, LenientNumbers()).Decode(obj)
+}
+
+func TestLenientNumbers(t *testing.T) {
+	s := NewSection()
+	s.SetUint8("flag", 1)
+	s.SetUint64("count", 100000)
+	s.SetArray("values", TypeUint8, Uint8Entry(1), Uint8Entry(2))
+
+	var obj CoercedObject
+	err := decodeLenient(s, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, CoercedObject{true, 100000, []uint64{1, 2}}, obj)
+}
+
+func TestLenientNumbersBool(t *testing.T) {
+	s := NewSection()
+	s.SetBool("count", true)
+
+	var obj CoercedObject
+	err := decodeLenient(s, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), obj.Count)
+}
+
+func TestLenientNumbersOverflow(t *testing.T) {
+	tests := []*Section{NewSection(), NewSection(), NewSection()}
+	tests[0].SetInt64("count", 1<<31)
+	tests[1].SetArray("values", TypeInt8, Int8Entry(-1))
+	tests[2].SetUint32("flag", 2)
+
+	for _, s := range tests {
+		var obj CoercedObject
+		err := decodeLenient(s, &obj)
+
+		assert.True(t, errors.Is(err, ErrValueOverflow), "%v", err)
+	}
+}
+
+func TestStrictNumbersByDefault(t *testing.T) {
+	s := NewSection()
+	s.SetUint32("txs", 7)
+
+	buffer := bytes.Buffer{}
+	err := WriteSection(&buffer, s)
+	assert.Nil(t, err)
+	data := buffer.Bytes()
+
+	var obj SimpleObject
+	err = Read(bytes.NewReader(data), &obj)
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+
+	err = NewDecoder(bytes.NewReader(data), LenientNumbers()).Decode(&obj)
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{7}, obj)
+}
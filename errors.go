@@ -0,0 +1,126 @@
+package moneroproto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedType  = errors.New("unsupported type")
+	ErrUnknownField     = errors.New("unknown field")
+	ErrPreambleMismatch = errors.New("message preamble mismatch")
+	ErrNotPointer       = errors.New("object is expected to be a non-nil pointer")
+	ErrNilElement       = errors.New("nil array element")
+	ErrNameTooLong      = errors.New("entry name is longer than 255 bytes")
+)
+
+// SerializationError is returned by the encoding and decoding functions of this package.
+// It wraps one of the package's sentinel errors (or an error of the underlying reader or writer)
+// so it can be matched with errors.Is
+type SerializationError struct {
+	// Path is the path of the field being processed, e.g. blocks[17].txs[3]. It's empty for the root object
+	Path string
+	// Offset is the number of bytes read or written when the error occurred
+	Offset int64
+	// Kind is the kind of the Go value being encoded or decoded
+	Kind reflect.Kind
+	// WireType is the wire type byte read from or about to be written into the stream, 0 if unknown
+	WireType byte
+	Err      error
+}
+
+func (e *SerializationError) Error() string {
+	b := strings.Builder{}
+	b.WriteString(e.Err.Error())
+
+	if len(e.Path) != 0 {
+		b.WriteString(": field ")
+		b.WriteString(e.Path)
+	}
+
+	b.WriteString(", offset ")
+	b.WriteString(strconv.FormatInt(e.Offset, 10))
+
+	if e.Kind != reflect.Invalid {
+		b.WriteString(", go kind ")
+		b.WriteString(e.Kind.String())
+	}
+
+	if e.WireType != 0 {
+		fmt.Fprintf(&b, ", wire type 0x%02x", e.WireType)
+	}
+
+	return b.String()
+}
+
+func (e *SerializationError) Unwrap() error {
+	return e.Err
+}
+
+func newSerializationError(err error, path fieldPath, offset int64, kind reflect.Kind, wireType byte) error {
+	if serr, ok := err.(*SerializationError); ok {
+		// the innermost error already knows where it happened
+		return serr
+	}
+
+	return &SerializationError{
+		Path:     path.String(),
+		Offset:   offset,
+		Kind:     kind,
+		WireType: wireType,
+		Err:      err,
+	}
+}
+
+// eofError converts the different flavours of a truncated stream into ErrUnexpectedEof
+func eofError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == ErrNotEnoughData {
+		return ErrUnexpectedEof
+	}
+
+	return err
+}
+
+type pathElem struct {
+	name  string
+	index int
+}
+
+// fieldPath tracks the position of the value being processed, it's rendered only when an error occurs
+type fieldPath []pathElem
+
+func (p *fieldPath) pushName(name string) {
+	*p = append(*p, pathElem{name: name, index: -1})
+}
+
+func (p *fieldPath) pushIndex(index int) {
+	*p = append(*p, pathElem{index: index})
+}
+
+func (p *fieldPath) pop() {
+	*p = (*p)[:len(*p)-1]
+}
+
+func (p fieldPath) String() string {
+	b := strings.Builder{}
+	for _, elem := range p {
+		if elem.index >= 0 {
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(elem.index))
+			b.WriteByte(']')
+			continue
+		}
+
+		if b.Len() != 0 {
+			b.WriteByte('.')
+		}
+
+		b.WriteString(elem.name)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncatedArrayObjectsDecode(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x07, 0x6f, 0x62, 0x6a,
+		0x65, 0x63, 0x74, 0x73, 0x8c, 0x0c, 0x04, 0x03, 0x74, 0x78, 0x73, 0x05, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22,
+		0x11, 0x04, 0x03, 0x74, 0x78, 0x73, 0x05, 0xff, 0x00})
+
+	var obj ArrayObjects
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedEof))
+
+	serr, ok := err.(*SerializationError)
+	assert.True(t, ok)
+	assert.Equal(t, "objects[1].txs", serr.Path)
+	assert.Equal(t, reflect.Uint64, serr.Kind)
+	assert.Equal(t, TypeUint64, serr.WireType)
+}
+
+type Uint32Array struct {
+	Array []uint32 `monerobinkv:"array"`
+}
+
+func TestArrayTypeMismatchDecode(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x61, 0x72, 0x72,
+		0x61, 0x79, 0x87, 0x14, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04, 0x00, 0x05, 0x00})
+
+	var obj Uint32Array
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+	assert.Equal(t, &SerializationError{
+		Path:     "array[0]",
+		Offset:   18,
+		Kind:     reflect.Uint32,
+		WireType: TypeUint16,
+		Err:      ErrUnexpectedType,
+	}, err)
+	assert.Equal(t, "unexpected type: field array[0], offset 18, go kind uint32, wire type 0x07", err.Error())
+}
+
+func TestUnknownWireTypeDecode(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x03, 0x74, 0x78, 0x73,
+		0x2a, 0x00})
+
+	var obj SimpleObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+}
+
+func TestPreambleMismatch(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00})
+
+	var obj SimpleObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrPreambleMismatch))
+}
+
+type UnsupportedObject struct {
+	Ch chan int `monerobinkv:"ch"`
+}
+
+func TestUnsupportedTypeEncode(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, UnsupportedObject{})
+
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+
+	serr, ok := err.(*SerializationError)
+	assert.True(t, ok)
+	assert.Equal(t, "ch", serr.Path)
+	assert.Equal(t, reflect.Chan, serr.Kind)
+}
+
+func TestCorruptedHashesSlice(t *testing.T) {
+	err, hashes := ByteSliceToHashes(make([]byte, 33))
+
+	assert.Equal(t, ErrLengthMismatch, err)
+	assert.Nil(t, hashes)
+}
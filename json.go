@@ -0,0 +1,690 @@
+package moneroproto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONOption configures the conversion of messages into JSON
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	hexStrings bool
+	annotate   bool
+}
+
+// HexStrings makes ToJSON render all binary strings as hex. By default only binary strings which
+// aren't printable UTF-8 text are rendered as hex
+func HexStrings() JSONOption {
+	return func(o *jsonOptions) {
+		o.hexStrings = true
+	}
+}
+
+// AnnotateTypes makes ToJSON preserve wire types, so the JSON converts back into the same message.
+// Numbers are wrapped into an object named after their wire type, e.g. {"$u64":5} or {"$f64":0.5}.
+// Arrays are wrapped the same way with the name of their element type, e.g. {"$u32":[1,2]},
+// {"$bin":["text"]}, {"$obj":[{}]} or {"$arr":[{"$u8":[1]}]}, and their numeric elements
+// aren't wrapped. Binary strings rendered as hex are wrapped into {"$hex":"..."}
+func AnnotateTypes() JSONOption {
+	return func(o *jsonOptions) {
+		o.annotate = true
+	}
+}
+
+// jsonTypeNames are the wire type names used by AnnotateTypes
+var jsonTypeNames = map[byte]string{
+	TypeInt64:        "$i64",
+	TypeInt32:        "$i32",
+	TypeInt16:        "$i16",
+	TypeInt8:         "$i8",
+	TypeUint64:       "$u64",
+	TypeUint32:       "$u32",
+	TypeUint16:       "$u16",
+	TypeUint8:        "$u8",
+	TypeDouble:       "$f64",
+	TypeBinaryString: "$bin",
+	TypeBool:         "$bool",
+	TypeObject:       "$obj",
+	TypeArray:        "$arr",
+}
+
+// jsonTypes are the wire types by their names used by AnnotateTypes
+var jsonTypes = func() map[string]byte {
+	types := make(map[string]byte, len(jsonTypeNames))
+	for t, name := range jsonTypeNames {
+		types[name] = t
+	}
+
+	return types
+}()
+
+const jsonHexName = "$hex"
+
+// ToJSON converts an encoded message, with or without MessagePreamble, into JSON. Sections become
+// objects keeping the order of entries and arrays become arrays. Binary strings are rendered as
+// text if they are printable UTF-8 and as hex otherwise, see HexStrings and AnnotateTypes
+func ToJSON(data []byte, opts ...JSONOption) ([]byte, error) {
+	w := jsonWriter{}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+
+	if bytes.HasPrefix(data, MessagePreamble) {
+		data = data[len(MessagePreamble):]
+	}
+
+	w.c = cursor{data: data}
+	err := w.writeObject(0)
+	if err != nil {
+		return nil, &SerializationError{
+			Path:   w.path.String(),
+			Offset: int64(w.c.pos),
+			Kind:   reflect.Invalid,
+			Err:    err,
+		}
+	}
+
+	return w.buf, nil
+}
+
+// jsonWriter renders a message walked by the cursor as JSON
+type jsonWriter struct {
+	c    cursor
+	buf  []byte
+	opts jsonOptions
+	path fieldPath
+}
+
+func (w *jsonWriter) writeObject(depth int) error {
+	if depth > DefaultLimits.MaxDepth {
+		return ErrDepthLimit
+	}
+
+	count, err := w.c.readVarint()
+	if err != nil {
+		return err
+	}
+
+	w.buf = append(w.buf, '{')
+	for i := uint64(0); i < count; i++ {
+		name, err := w.c.readName()
+		if err != nil {
+			return err
+		}
+
+		if i != 0 {
+			w.buf = append(w.buf, ',')
+		}
+		w.writeString(string(name))
+		w.buf = append(w.buf, ':')
+
+		t, err := w.c.readByte()
+		if err != nil {
+			return err
+		}
+
+		w.path.pushName(string(name))
+		err = w.writeValue(t, depth+1)
+		if err != nil {
+			return err
+		}
+		w.path.pop()
+	}
+	w.buf = append(w.buf, '}')
+
+	return nil
+}
+
+// writeValue renders a value of wire type t which has its type tag
+func (w *jsonWriter) writeValue(t byte, depth int) error {
+	switch {
+	case t&FlagArray != 0:
+		return w.writeArray(t & ^FlagArray, depth)
+	case t == TypeObject:
+		return w.writeObject(depth)
+	case t == TypeArray:
+		// an element of an array of arrays
+		arrayType, err := w.c.readByte()
+		if err != nil {
+			return err
+		}
+
+		if arrayType&FlagArray == 0 {
+			return ErrUnexpectedType
+		}
+
+		return w.writeArray(arrayType & ^FlagArray, depth)
+	}
+
+	name, number := jsonTypeNames[t]
+	number = number && t != TypeBinaryString && t != TypeBool
+	if w.opts.annotate && number {
+		w.buf = append(w.buf, '{')
+		w.writeString(name)
+		w.buf = append(w.buf, ':')
+	}
+
+	err := w.writeScalar(t)
+	if err != nil {
+		return err
+	}
+
+	if w.opts.annotate && number {
+		w.buf = append(w.buf, '}')
+	}
+
+	return nil
+}
+
+func (w *jsonWriter) writeArray(elemType byte, depth int) error {
+	if depth > DefaultLimits.MaxDepth {
+		return ErrDepthLimit
+	}
+
+	name, ok := jsonTypeNames[elemType]
+	if !ok {
+		return ErrUnexpectedType
+	}
+
+	count, err := w.c.readVarint()
+	if err != nil {
+		return err
+	}
+
+	if w.opts.annotate {
+		w.buf = append(w.buf, '{')
+		w.writeString(name)
+		w.buf = append(w.buf, ':')
+	}
+
+	w.buf = append(w.buf, '[')
+	for i := uint64(0); i < count; i++ {
+		if i != 0 {
+			w.buf = append(w.buf, ',')
+		}
+
+		w.path.pushIndex(int(i))
+		switch elemType {
+		case TypeObject, TypeArray:
+			err = w.writeValue(elemType, depth+1)
+		default:
+			err = w.writeScalar(elemType)
+		}
+
+		if err != nil {
+			return err
+		}
+		w.path.pop()
+	}
+	w.buf = append(w.buf, ']')
+
+	if w.opts.annotate {
+		w.buf = append(w.buf, '}')
+	}
+
+	return nil
+}
+
+// writeScalar renders a value of wire type t without an annotation
+func (w *jsonWriter) writeScalar(t byte) error {
+	switch t {
+	case TypeDouble:
+		buf, err := w.c.fixed(t, t, 8)
+		if err != nil {
+			return err
+		}
+
+		val := bytesToFloat64(buf)
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return ErrUnsupportedType
+		}
+
+		w.buf = strconv.AppendFloat(w.buf, val, 'g', -1, 64)
+	case TypeBool:
+		buf, err := w.c.fixed(t, t, 1)
+		if err != nil {
+			return err
+		}
+
+		w.buf = strconv.AppendBool(w.buf, buf[0] == 1)
+	case TypeBinaryString:
+		size, err := w.c.readVarint()
+		if err == nil {
+			err = w.c.need(size)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		w.writeBinaryString(w.c.data[w.c.pos : w.c.pos+int(size)])
+		w.c.pos += int(size)
+	default:
+		val, negative, err := w.c.integer(t)
+		if err != nil {
+			return err
+		}
+
+		if negative {
+			w.buf = strconv.AppendInt(w.buf, int64(val), 10)
+		} else {
+			w.buf = strconv.AppendUint(w.buf, val, 10)
+		}
+	}
+
+	return nil
+}
+
+func (w *jsonWriter) writeBinaryString(val []byte) {
+	if !w.opts.hexStrings && isPrintable(val) {
+		w.writeString(string(val))
+		return
+	}
+
+	if w.opts.annotate {
+		w.buf = append(w.buf, '{')
+		w.writeString(jsonHexName)
+		w.buf = append(w.buf, ':')
+	}
+
+	w.buf = append(w.buf, '"')
+	w.buf = append(w.buf, hex.EncodeToString(val)...)
+	w.buf = append(w.buf, '"')
+
+	if w.opts.annotate {
+		w.buf = append(w.buf, '}')
+	}
+}
+
+func (w *jsonWriter) writeString(val string) {
+	// marshaling a string can't fail
+	str, _ := json.Marshal(val)
+	w.buf = append(w.buf, str...)
+}
+
+// isPrintable reports whether val is UTF-8 text without control characters
+func isPrintable(val []byte) bool {
+	if !utf8.Valid(val) {
+		return false
+	}
+
+	for _, r := range string(val) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FromJSON builds a message including MessagePreamble from JSON, the root must be an object.
+// The message has the same layout Write produces. Values get these wire types:
+//
+//	strings - binary strings of their UTF-8 bytes
+//	non-negative integers - TypeUint64, negative ones - TypeInt64, other numbers - TypeDouble
+//	arrays - arrays of the type of their elements, which must agree. Numbers of an array become
+//	TypeInt64 if any of them is negative and TypeDouble if any isn't an integer. Empty arrays
+//	are arrays of sections
+//
+// An object with a single member named after a wire type the way AnnotateTypes writes it is a value
+// of that type, e.g. {"$u32":5}, {"$u32":[1,2]} or {"$obj":[]}, and {"$hex":"00ff"} is a binary string.
+// So the output of ToJSON with AnnotateTypes converts back into the original message
+func FromJSON(data []byte) ([]byte, error) {
+	r := jsonReader{dec: json.NewDecoder(bytes.NewReader(data))}
+	r.dec.UseNumber()
+
+	s, err := r.readRoot()
+	if err != nil {
+		return nil, &SerializationError{
+			Path: r.path.String(),
+			Kind: reflect.Invalid,
+			Err:  err,
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	err = WriteSection(&buffer, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// jsonReader builds entries from JSON tokens
+type jsonReader struct {
+	dec  *json.Decoder
+	path fieldPath
+}
+
+func (r *jsonReader) readRoot() (*Section, error) {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok != json.Delim('{') {
+		return nil, ErrUnexpectedType
+	}
+
+	entry, err := r.readObject(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Type != TypeObject {
+		return nil, ErrUnexpectedType
+	}
+
+	_, err = r.dec.Token()
+	if err != io.EOF {
+		return nil, ErrLengthMismatch
+	}
+
+	return entry.Value.(*Section), nil
+}
+
+// readValue reads a value into an entry. hint is the expected wire type, 0 if it's inferred
+func (r *jsonReader) readValue(hint byte, depth int) (Entry, error) {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return r.readToken(tok, hint, depth)
+}
+
+func (r *jsonReader) readToken(tok json.Token, hint byte, depth int) (Entry, error) {
+	if depth > DefaultLimits.MaxDepth {
+		return Entry{}, ErrDepthLimit
+	}
+
+	var entry Entry
+	switch tok := tok.(type) {
+	case json.Number:
+		return numberEntry(tok, hint)
+	case string:
+		entry = StringEntry(tok)
+	case bool:
+		entry = BoolEntry(tok)
+	case json.Delim:
+		var err error
+		if tok == '[' {
+			entry, err = r.readArray(0, depth+1)
+		} else {
+			entry, err = r.readObject(depth + 1)
+		}
+
+		if err != nil {
+			return Entry{}, err
+		}
+	default:
+		// null
+		return Entry{}, ErrUnsupportedType
+	}
+
+	if hint != 0 && entry.Type != hint && !(hint == TypeArray && entry.IsArray()) {
+		return Entry{}, ErrUnexpectedType
+	}
+
+	return entry, nil
+}
+
+// readObject reads the rest of an object which is either a section or an annotated value
+func (r *jsonReader) readObject(depth int) (Entry, error) {
+	s := NewSection()
+	for i := 0; r.dec.More(); i++ {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return Entry{}, err
+		}
+
+		name := tok.(string)
+		if i == 0 && strings.HasPrefix(name, "$") {
+			if t, ok := jsonTypes[name]; ok || name == jsonHexName {
+				return r.readAnnotated(t, depth)
+			}
+		}
+
+		r.path.pushName(name)
+		if len(name) > math.MaxUint8 {
+			return Entry{}, ErrNameTooLong
+		}
+
+		entry, err := r.readValue(0, depth)
+		if err != nil {
+			return Entry{}, err
+		}
+		r.path.pop()
+
+		s.Set(name, entry)
+	}
+
+	_, err := r.dec.Token()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return SectionEntry(s), nil
+}
+
+// readAnnotated reads the value of an object annotating it with wire type t, t is 0 for hex strings
+func (r *jsonReader) readAnnotated(t byte, depth int) (Entry, error) {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var entry Entry
+	switch {
+	case t == 0:
+		str, ok := tok.(string)
+		if !ok {
+			return Entry{}, ErrUnexpectedType
+		}
+
+		val, err := hex.DecodeString(str)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		entry = BinaryStringEntry(val)
+	case tok == json.Delim('['):
+		entry, err = r.readArray(t, depth)
+	default:
+		entry, err = r.readToken(tok, t, depth)
+	}
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// the annotation must be the only member of the object
+	tok, err = r.dec.Token()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if tok != json.Delim('}') {
+		return Entry{}, ErrUnexpectedType
+	}
+
+	return entry, nil
+}
+
+// readArray reads the rest of an array of elemType elements, elemType is 0 if it's inferred
+func (r *jsonReader) readArray(elemType byte, depth int) (Entry, error) {
+	elems := []Entry{}
+	for i := 0; r.dec.More(); i++ {
+		r.path.pushIndex(i)
+		elem, err := r.readValue(elemType, depth)
+		if err != nil {
+			return Entry{}, err
+		}
+		r.path.pop()
+
+		elems = append(elems, elem)
+	}
+
+	_, err := r.dec.Token()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if elemType == 0 {
+		return r.inferArray(elems)
+	}
+
+	return ArrayEntry(elemType, elems...), nil
+}
+
+// inferArray builds an array of elements read without a type annotation, see FromJSON
+func (r *jsonReader) inferArray(elems []Entry) (Entry, error) {
+	if len(elems) == 0 {
+		return ArrayEntry(TypeObject), nil
+	}
+
+	elemType := arrayElemType(elems[0])
+	for i := 1; i < len(elems); i++ {
+		t := arrayElemType(elems[i])
+		switch {
+		case t == elemType:
+		case isInferredNumber(t) && isInferredNumber(elemType):
+			if t == TypeDouble || elemType == TypeDouble {
+				elemType = TypeDouble
+			} else {
+				elemType = TypeInt64
+			}
+		default:
+			r.path.pushIndex(i)
+			return Entry{}, ErrUnexpectedType
+		}
+	}
+
+	if !isInferredNumber(elemType) {
+		return ArrayEntry(elemType, elems...), nil
+	}
+
+	for i, elem := range elems {
+		converted, err := widenNumber(elem, elemType)
+		if err != nil {
+			r.path.pushIndex(i)
+			return Entry{}, err
+		}
+
+		elems[i] = converted
+	}
+
+	return ArrayEntry(elemType, elems...), nil
+}
+
+// arrayElemType returns the type elem has as an array element
+func arrayElemType(elem Entry) byte {
+	if elem.IsArray() {
+		return TypeArray
+	}
+
+	return elem.Type
+}
+
+// isInferredNumber reports whether t is one of the wire types numberEntry infers
+func isInferredNumber(t byte) bool {
+	return t == TypeUint64 || t == TypeInt64 || t == TypeDouble
+}
+
+// widenNumber converts a number entry of an inferred type into wire type t which is at least as wide
+func widenNumber(elem Entry, t byte) (Entry, error) {
+	if elem.Type == t {
+		return elem, nil
+	}
+
+	if t == TypeDouble {
+		if elem.Type == TypeInt64 {
+			return DoubleEntry(float64(elem.Value.(int64))), nil
+		}
+
+		return DoubleEntry(float64(elem.Value.(uint64))), nil
+	}
+
+	val := elem.Value.(uint64)
+	if val > math.MaxInt64 {
+		return Entry{}, ErrValueOverflow
+	}
+
+	return Int64Entry(int64(val)), nil
+}
+
+// numberEntry converts a number into an entry of wire type t, see FromJSON for inferred types
+func numberEntry(n json.Number, t byte) (Entry, error) {
+	str := string(n)
+	if t == 0 {
+		if val, err := strconv.ParseUint(str, 10, 64); err == nil {
+			return Uint64Entry(val), nil
+		}
+
+		if val, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return Int64Entry(val), nil
+		}
+
+		t = TypeDouble
+	}
+
+	if t == TypeDouble {
+		val, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return Entry{}, ErrValueOverflow
+		}
+
+		return DoubleEntry(val), nil
+	}
+
+	if !isIntegerWireType(t) {
+		return Entry{}, ErrUnexpectedType
+	}
+
+	min, max := integerWireRange(t)
+	var bits uint64
+	if strings.HasPrefix(str, "-") {
+		val, err := strconv.ParseInt(str, 10, 64)
+		if err != nil || val < min {
+			return Entry{}, ErrValueOverflow
+		}
+
+		bits = uint64(val)
+	} else {
+		val, err := strconv.ParseUint(str, 10, 64)
+		if err != nil || val > max {
+			return Entry{}, ErrValueOverflow
+		}
+
+		bits = val
+	}
+
+	switch t {
+	case TypeInt64:
+		return Int64Entry(int64(bits)), nil
+	case TypeInt32:
+		return Int32Entry(int32(bits)), nil
+	case TypeInt16:
+		return Int16Entry(int16(bits)), nil
+	case TypeInt8:
+		return Int8Entry(int8(bits)), nil
+	case TypeUint64:
+		return Uint64Entry(bits), nil
+	case TypeUint32:
+		return Uint32Entry(uint32(bits)), nil
+	case TypeUint16:
+		return Uint16Entry(uint16(bits)), nil
+	}
+
+	return Uint8Entry(uint8(bits)), nil
+}
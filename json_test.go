@@ -0,0 +1,173 @@
+package moneroproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeJSONTestMessage(t *testing.T) []byte {
+	inner := NewSection()
+	inner.SetInt8("delta", -3)
+
+	s := NewSection()
+	s.SetString("status", "OK")
+	s.SetBinaryString("blob", []byte{0x00, 0xff})
+	s.SetUint64("height", 445566)
+	s.SetDouble("difficulty", 0.5)
+	s.SetBool("untrusted", true)
+	s.SetSection("inner", inner)
+	s.SetArray("indices", TypeUint32, Uint32Entry(1), Uint32Entry(2))
+	s.SetArray("empty", TypeObject)
+	s.SetArray("nested", TypeArray, ArrayEntry(TypeUint8, Uint8Entry(7)))
+
+	buffer := bytes.Buffer{}
+	err := WriteSection(&buffer, s)
+	assert.Nil(t, err)
+	return buffer.Bytes()
+}
+
+func TestToJSON(t *testing.T) {
+	data := makeJSONTestMessage(t)
+
+	str, err := ToJSON(data)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"status":"OK","blob":"00ff","height":445566,"difficulty":0.5,"untrusted":true,`+
+		`"inner":{"delta":-3},"indices":[1,2],"empty":[],"nested":[[7]]}`, string(str))
+	assert.True(t, json.Valid(str))
+
+	// the preamble is optional
+	withoutPreamble, err := ToJSON(data[len(MessagePreamble):])
+	assert.Nil(t, err)
+	assert.Equal(t, str, withoutPreamble)
+}
+
+func TestToJSONAnnotated(t *testing.T) {
+	str, err := ToJSON(makeJSONTestMessage(t), AnnotateTypes())
+	assert.Nil(t, err)
+	assert.Equal(t, `{"status":"OK","blob":{"$hex":"00ff"},"height":{"$u64":445566},"difficulty":{"$f64":0.5},`+
+		`"untrusted":true,"inner":{"delta":{"$i8":-3}},"indices":{"$u32":[1,2]},"empty":{"$obj":[]},`+
+		`"nested":{"$arr":[{"$u8":[7]}]}}`, string(str))
+}
+
+func TestToJSONHexStrings(t *testing.T) {
+	data, err := Marshal(BlockCompleteEntry{Block: []byte("block"), Txs: [][]byte{[]byte("tx")}})
+	assert.Nil(t, err)
+
+	str, err := ToJSON(data, HexStrings())
+	assert.Nil(t, err)
+	assert.Equal(t, `{"pruned":false,"block":"626c6f636b","block_weight":0,"txs":["7478"]}`, string(str))
+}
+
+func TestToJSONTruncated(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	_, err = ToJSON(data[:len(data)-1])
+	assert.True(t, errors.Is(err, ErrUnexpectedEof))
+	assert.Equal(t, "top_hash", err.(*SerializationError).Path)
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	full, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	for _, data := range [][]byte{makeJSONTestMessage(t), full} {
+		str, err := ToJSON(data, AnnotateTypes())
+		assert.Nil(t, err)
+
+		converted, err := FromJSON(str)
+		assert.Nil(t, err)
+		assert.Equal(t, data, converted)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	str := `{"client": "wallet", "block_ids": {"$hex": ""}, "start_height": {"$u64": 100}, "prune": true,
+		"no_miner_tx": false, "extra": {"$i32": [-1, 2]}, "sections": [{"id": 1}, {"id": 2}]}`
+
+	data, err := FromJSON([]byte(str))
+	assert.Nil(t, err)
+
+	var req GetBlocksFastRequest
+	err = ReadStrict(bytes.NewReader(data), &req)
+	assert.True(t, errors.Is(err, ErrUnknownField))
+
+	s, err := ReadSection(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	extra, ok := s.Get("extra")
+	assert.True(t, ok)
+	assert.Equal(t, ArrayEntry(TypeInt32, Int32Entry(-1), Int32Entry(2)), extra)
+
+	sections, ok := s.Get("sections")
+	assert.True(t, ok)
+	assert.Equal(t, TypeObject|FlagArray, sections.Type)
+
+	id, err := GetUint64(data, "sections[1].id")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), id)
+
+	s.Delete("extra")
+	s.Delete("sections")
+	buffer := bytes.Buffer{}
+	err = WriteSection(&buffer, s)
+	assert.Nil(t, err)
+
+	expected, err := Marshal(GetBlocksFastRequest{Client: "wallet", StartHeight: 100, Prune: true})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestFromJSONInferredArrays(t *testing.T) {
+	data, err := FromJSON([]byte(`{"a": [1, -1], "b": [1, 2.5, -3], "c": [1, 2], "d": [[1], ["x"]]}`))
+	assert.Nil(t, err)
+
+	s, err := ReadSection(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	a, _ := s.Get("a")
+	assert.Equal(t, ArrayEntry(TypeInt64, Int64Entry(1), Int64Entry(-1)), a)
+	b, _ := s.Get("b")
+	assert.Equal(t, ArrayEntry(TypeDouble, DoubleEntry(1), DoubleEntry(2.5), DoubleEntry(-3)), b)
+	c, _ := s.Get("c")
+	assert.Equal(t, ArrayEntry(TypeUint64, Uint64Entry(1), Uint64Entry(2)), c)
+	d, _ := s.Get("d")
+	assert.Equal(t, TypeArray|FlagArray, d.Type)
+
+	_, err = FromJSON([]byte(`{"a": [-1, 18446744073709551615]}`))
+	assert.True(t, errors.Is(err, ErrValueOverflow), "%v", err)
+	assert.Equal(t, "a[1]", err.(*SerializationError).Path)
+
+	_, err = FromJSON([]byte(`{"a": [1, true]}`))
+	assert.True(t, errors.Is(err, ErrUnexpectedType), "%v", err)
+	assert.Equal(t, "a[1]", err.(*SerializationError).Path)
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []string{
+		`{"height": {"$u8": 300}}`,
+		`{"height": {"$u64": -1}}`,
+		`{"height": null}`,
+		`{"height": {"$u64": 1, "extra": 2}}`,
+		`{"heights": [1, "two"]}`,
+		`{"$u64": 1}`,
+		`[]`,
+	}
+	expected := []error{ErrValueOverflow, ErrValueOverflow, ErrUnsupportedType, ErrUnexpectedType, ErrUnexpectedType,
+		ErrUnexpectedType, ErrUnexpectedType}
+
+	for i, str := range tests {
+		_, err := FromJSON([]byte(str))
+		assert.True(t, errors.Is(err, expected[i]), "%s: %v", str, err)
+	}
+
+	name := strings.Repeat("n", 256)
+	_, err := FromJSON([]byte(`{"blocks": [{"` + name + `": 1}]}`))
+	assert.True(t, errors.Is(err, ErrNameTooLong), "%v", err)
+	assert.Equal(t, "blocks[0]."+name, err.(*SerializationError).Path)
+}
@@ -0,0 +1,156 @@
+package moneroproto
+
+import (
+	"errors"
+	"io"
+	"math"
+	"reflect"
+)
+
+var (
+	ErrDepthLimit      = errors.New("nesting depth limit exceeded")
+	ErrTooManyEntries  = errors.New("section entries limit exceeded")
+	ErrArrayTooLong    = errors.New("array length limit exceeded")
+	ErrBlobTooLarge    = errors.New("binary string size limit exceeded")
+	ErrAllocationLimit = errors.New("allocation budget exceeded")
+)
+
+// Limits restricts resources a decoder may spend on a single message, so a hostile peer can't
+// make it allocate an arbitrary amount of memory or recurse without bound. A zero field takes its
+// value from DefaultLimits, set a field to the maximum value of its type to lift the limit
+type Limits struct {
+	// MaxDepth is the maximum nesting of sections and arrays, the root section has depth 1
+	MaxDepth int
+	// MaxSectionEntries is the maximum number of entries in a single section
+	MaxSectionEntries uint64
+	// MaxArrayLength is the maximum number of elements in a single array
+	MaxArrayLength uint64
+	// MaxBlobSize is the maximum size of a single binary string in bytes
+	MaxBlobSize uint64
+	// MaxAllocation is the total number of bytes the decoder may allocate for strings, slices and maps
+	MaxAllocation uint64
+}
+
+// DefaultLimits are applied by Read, ReadStrict and ReadSection. They follow epee's recursion limit
+// and levin's maximum packet size
+var DefaultLimits = Limits{
+	MaxDepth:          100,
+	MaxSectionEntries: 1 << 16,
+	MaxArrayLength:    1 << 20,
+	MaxBlobSize:       100000000,
+	MaxAllocation:     1 << 28,
+}
+
+// maxDecodedLength bounds array lengths and binary string sizes whatever the limits are, so
+// a size read from the message always fits int when it's allocated
+const maxDecodedLength = math.MaxInt32
+
+var entrySize = uint64(reflect.TypeOf(Entry{}).Size())
+
+// withDefaults returns l with zero fields taken from DefaultLimits
+func (l Limits) withDefaults() Limits {
+	if l.MaxDepth == 0 {
+		l.MaxDepth = DefaultLimits.MaxDepth
+	}
+
+	if l.MaxSectionEntries == 0 {
+		l.MaxSectionEntries = DefaultLimits.MaxSectionEntries
+	}
+
+	if l.MaxArrayLength == 0 {
+		l.MaxArrayLength = DefaultLimits.MaxArrayLength
+	}
+
+	if l.MaxBlobSize == 0 {
+		l.MaxBlobSize = DefaultLimits.MaxBlobSize
+	}
+
+	if l.MaxAllocation == 0 {
+		l.MaxAllocation = DefaultLimits.MaxAllocation
+	}
+
+	return l
+}
+
+// enter increases the nesting depth, every call must be paired with leave
+func (d *decoder) enter() error {
+	d.depth++
+	if d.limits.MaxDepth != 0 && d.depth > d.limits.MaxDepth {
+		return ErrDepthLimit
+	}
+
+	return nil
+}
+
+func (d *decoder) leave() {
+	d.depth--
+}
+
+func (d *decoder) checkSectionEntries(size uint64) error {
+	if d.limits.MaxSectionEntries != 0 && size > d.limits.MaxSectionEntries {
+		return ErrTooManyEntries
+	}
+
+	return nil
+}
+
+// checkArray checks an array of size elements, each taking elemSize bytes of memory. elemSize is 0
+// if the elements aren't going to be stored
+func (d *decoder) checkArray(size uint64, elemSize uint64) error {
+	if size > maxDecodedLength || (d.limits.MaxArrayLength != 0 && size > d.limits.MaxArrayLength) {
+		return ErrArrayTooLong
+	}
+
+	return d.allocate(size, elemSize)
+}
+
+// allocate charges count items of elemSize bytes each against the allocation budget
+func (d *decoder) allocate(count uint64, elemSize uint64) error {
+	if d.limits.MaxAllocation == 0 {
+		return nil
+	}
+
+	if elemSize != 0 && count > math.MaxUint64/elemSize {
+		return ErrAllocationLimit
+	}
+
+	size := count * elemSize
+	if size > d.limits.MaxAllocation-d.allocated {
+		return ErrAllocationLimit
+	}
+
+	d.allocated += size
+	return nil
+}
+
+// readBinaryString reads a binary string checking its size against the limits before allocating it.
+// In no-copy mode the string aliases the decoded message instead
+func (d *decoder) readBinaryString() ([]byte, error) {
+	size, err := d.unpackVarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if size > maxDecodedLength || (d.limits.MaxBlobSize != 0 && size > d.limits.MaxBlobSize) {
+		return nil, ErrBlobTooLarge
+	}
+
+	if r, ok := d.reader.(*sliceReader); ok && d.noCopy {
+		buf, err := r.next(size)
+		d.offset += int64(len(buf))
+		return buf, err
+	}
+
+	err = d.allocate(size, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	_, err = io.ReadFull(d, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,113 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobTooLarge(t *testing.T) {
+	// {"block": <1 GiB binary string>}
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x62, 0x6c, 0x6f,
+		0x63, 0x6b, 0x0a, 0xfe, 0xff, 0xff, 0xff})
+
+	var obj BlockCompleteEntry
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrBlobTooLarge))
+	assert.Equal(t, "block", err.(*SerializationError).Path)
+}
+
+func TestArrayTooLong(t *testing.T) {
+	// {"array": <2^62-1 uint32 values>}
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x61, 0x72, 0x72,
+		0x61, 0x79, 0x86, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	var obj Uint32Array
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrArrayTooLong))
+	assert.Equal(t, "array", err.(*SerializationError).Path)
+}
+
+func TestArrayTooLongWithPartialLimits(t *testing.T) {
+	// {"array": <2^38 uint32 values>}
+	data := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x61, 0x72, 0x72, 0x61, 0x79, 0x86,
+		0x03, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00}
+	limits := []Limits{
+		{MaxDepth: 10},
+		{MaxArrayLength: math.MaxUint64, MaxAllocation: math.MaxUint64},
+	}
+
+	for _, l := range limits {
+		var obj Uint32Array
+		err := ReadWithLimits(bytes.NewReader(data), &obj, l)
+
+		assert.True(t, errors.Is(err, ErrArrayTooLong), "%v", err)
+	}
+}
+
+func TestArrayAllocationCharged(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, Uint32Array{Array: []uint32{1, 2, 3, 4}})
+	assert.Nil(t, err)
+
+	var obj Uint32Array
+	err = ReadWithLimits(bytes.NewReader(buffer.Bytes()), &obj, Limits{MaxAllocation: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, cap(obj.Array))
+}
+
+func TestTooManyEntries(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0xfe, 0xff, 0xff, 0xff})
+
+	s, err := ReadSection(reader)
+
+	assert.Nil(t, s)
+	assert.True(t, errors.Is(err, ErrTooManyEntries))
+}
+
+func TestDepthLimit(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, OptionalObject{Block: &SimpleObject{1}})
+	assert.Nil(t, err)
+
+	var obj OptionalObject
+	err = ReadWithLimits(bytes.NewReader(buffer.Bytes()), &obj, Limits{MaxDepth: 2})
+	assert.Nil(t, err)
+
+	err = ReadWithLimits(bytes.NewReader(buffer.Bytes()), &obj, Limits{MaxDepth: 1})
+	assert.True(t, errors.Is(err, ErrDepthLimit))
+	assert.Equal(t, "block", err.(*SerializationError).Path)
+}
+
+func TestDepthLimitSkipped(t *testing.T) {
+	// {"a": {"a": {"a": ... }}} nested deeper than the default limit
+	data := append([]byte{}, MessagePreamble...)
+	for i := 0; i < DefaultLimits.MaxDepth; i++ {
+		data = append(data, 0x04, 0x01, 0x61, 0x0c)
+	}
+	data = append(data, 0x00)
+
+	var obj SimpleObject
+	err := Read(bytes.NewReader(data), &obj)
+
+	assert.True(t, errors.Is(err, ErrDepthLimit))
+}
+
+func TestAllocationLimit(t *testing.T) {
+	obj := BlockCompleteEntry{Block: []byte("block"), Txs: [][]byte{[]byte("tx1"), []byte("tx2")}}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var restored BlockCompleteEntry
+	err = ReadWithLimits(bytes.NewReader(buffer.Bytes()), &restored, Limits{MaxAllocation: 8})
+
+	assert.True(t, errors.Is(err, ErrAllocationLimit))
+	assert.Equal(t, "txs", err.(*SerializationError).Path)
+}
@@ -0,0 +1,131 @@
+package moneroproto
+
+import (
+	"reflect"
+)
+
+// Marshaler is implemented by types which encode themselves into a portable storage entry.
+// A type used as a whole message must marshal into a TypeObject entry
+type Marshaler interface {
+	MarshalBinKV() (Entry, error)
+}
+
+// Unmarshaler is implemented by types which decode themselves from a portable storage entry.
+// The entry is read according to the wire type found in the message, arrays of arrays are
+// passed as array entries and nested objects as section entries
+type Unmarshaler interface {
+	UnmarshalBinKV(entry Entry) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// isMarshalerType reports whether values of type t stored in a slice or in an addressable
+// struct implement Marshaler
+func isMarshalerType(t reflect.Type) bool {
+	return t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType)
+}
+
+// marshalerOf returns v as a Marshaler. Pointer receivers are used only if v is addressable
+func marshalerOf(v reflect.Value) (Marshaler, bool) {
+	if !v.IsValid() || isNilPointer(v) {
+		return nil, false
+	}
+
+	if v.Type().Implements(marshalerType) && v.CanInterface() {
+		return v.Interface().(Marshaler), true
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerType) && v.Addr().CanInterface() {
+		return v.Addr().Interface().(Marshaler), true
+	}
+
+	return nil, false
+}
+
+// unmarshalerOf returns v as an Unmarshaler, v must be addressable
+func unmarshalerOf(v reflect.Value) (Unmarshaler, bool) {
+	if !v.CanAddr() || !v.Addr().CanInterface() {
+		return nil, false
+	}
+
+	u, ok := v.Addr().Interface().(Unmarshaler)
+	return u, ok
+}
+
+// encodeMarshaler writes the entry returned by m along with its type tag
+func (e *encoder) encodeMarshaler(m Marshaler, kind reflect.Kind, level int) error {
+	entry, err := m.MarshalBinKV()
+	if err != nil {
+		return e.error(err, kind, 0)
+	}
+
+	if level == 0 && entry.Type != TypeObject {
+		return e.error(ErrUnexpectedType, kind, entry.Type)
+	}
+
+	return e.writeEntry(entry, level)
+}
+
+// encodeMarshalerArray writes a slice of Marshaler values. All the elements must marshal into
+// entries of the same type, the type of an empty slice is taken from the element's zero value
+func (e *encoder) encodeMarshalerArray(value reflect.Value) error {
+	elems := make([]Entry, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		e.path.pushIndex(i)
+		elem := value.Index(i)
+		if isNilPointer(elem) {
+			return e.error(ErrNilElement, elem.Kind(), 0)
+		}
+
+		m, _ := marshalerOf(elem)
+		entry, err := m.MarshalBinKV()
+		if err != nil {
+			return e.error(err, elem.Kind(), 0)
+		}
+		e.path.pop()
+
+		elems = append(elems, entry)
+	}
+
+	var elemType byte
+	if len(elems) != 0 {
+		elemType = elems[0].Type
+	} else {
+		zero := reflect.New(value.Type().Elem()).Elem()
+		if zero.Kind() == reflect.Ptr {
+			zero.Set(reflect.New(zero.Type().Elem()))
+		}
+
+		m, _ := marshalerOf(zero)
+		entry, err := m.MarshalBinKV()
+		if err != nil {
+			return e.error(err, zero.Kind(), 0)
+		}
+
+		elemType = entry.Type
+	}
+
+	if elemType&FlagArray != 0 {
+		elemType = TypeArray
+	}
+
+	return e.writeEntry(ArrayEntry(elemType, elems...), 1)
+}
+
+// decodeUnmarshaler reads a value of the given wire type as an entry and passes it to u
+func (d *decoder) decodeUnmarshaler(u Unmarshaler, valueType byte, kind reflect.Kind) error {
+	entry, err := d.readEntry(valueType)
+	if err != nil {
+		return err
+	}
+
+	err = u.UnmarshalBinKV(entry)
+	if err != nil {
+		return d.error(err, kind, valueType)
+	}
+
+	return nil
+}
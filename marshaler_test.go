@@ -0,0 +1,168 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errNegativeAmount = errors.New("negative amount")
+
+// amount is sent as a signed integer by some peers, it's kept unsigned in Go
+type amount struct {
+	atomic uint64
+}
+
+func (a amount) MarshalBinKV() (Entry, error) {
+	return Uint64Entry(a.atomic), nil
+}
+
+func (a *amount) UnmarshalBinKV(entry Entry) error {
+	if entry.Type == TypeInt64 {
+		val, _ := entry.Int64()
+		if val < 0 {
+			return errNegativeAmount
+		}
+
+		a.atomic = uint64(val)
+		return nil
+	}
+
+	val, err := entry.Uint64()
+	a.atomic = val
+	return err
+}
+
+type address struct {
+	spend [4]byte
+	view  [4]byte
+}
+
+func (a address) MarshalBinKV() (Entry, error) {
+	s := NewSection()
+	s.SetBinaryString("spend", a.spend[:])
+	s.SetBinaryString("view", a.view[:])
+	return SectionEntry(s), nil
+}
+
+func (a *address) UnmarshalBinKV(entry Entry) error {
+	s, err := entry.Section()
+	if err != nil {
+		return err
+	}
+
+	spend, err := s.GetBinaryString("spend")
+	if err != nil {
+		return err
+	}
+
+	view, err := s.GetBinaryString("view")
+	if err != nil {
+		return err
+	}
+
+	copy(a.spend[:], spend)
+	copy(a.view[:], view)
+	return nil
+}
+
+type AmountObject struct {
+	Amount amount `monerobinkv:"amount"`
+}
+
+type TransferObject struct {
+	Amount      amount     `monerobinkv:"amount"`
+	Fee         *amount    `monerobinkv:"fee"`
+	Amounts     []amount   `monerobinkv:"amounts"`
+	Splits      [][]amount `monerobinkv:"splits"`
+	Destination address    `monerobinkv:"destination"`
+	Change      []address  `monerobinkv:"change"`
+}
+
+func TestMarshalerEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+		0x74, 0x05, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, AmountObject{amount{5}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestUnmarshalerDecode(t *testing.T) {
+	// {"amount": int64(5)}
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x06, 0x61, 0x6d, 0x6f,
+		0x75, 0x6e, 0x74, 0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+	var obj AmountObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, AmountObject{amount{5}}, obj)
+}
+
+func TestUnmarshalerError(t *testing.T) {
+	// {"amount": int64(-1)}
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x06, 0x61, 0x6d, 0x6f,
+		0x75, 0x6e, 0x74, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	var obj AmountObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, errNegativeAmount))
+	assert.Equal(t, "amount", err.(*SerializationError).Path)
+}
+
+func TestMarshalerSerialize(t *testing.T) {
+	obj := TransferObject{
+		Amount:      amount{1},
+		Fee:         &amount{2},
+		Amounts:     []amount{{3}, {4}},
+		Splits:      [][]amount{{{5}}, {}},
+		Destination: address{[4]byte{1, 2, 3, 4}, [4]byte{5, 6, 7, 8}},
+		Change:      []address{},
+	}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var restored TransferObject
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, obj, restored)
+
+	s, err := ReadSection(bytes.NewReader(buffer.Bytes()))
+	assert.Nil(t, err)
+
+	amounts, ok := s.Get("amounts")
+	assert.True(t, ok)
+	assert.Equal(t, TypeUint64|FlagArray, amounts.Type)
+
+	change, ok := s.Get("change")
+	assert.True(t, ok)
+	assert.Equal(t, TypeObject|FlagArray, change.Type)
+}
+
+func TestMarshalerNotObjectMessage(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, amount{1})
+
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+}
+
+func TestUnmarshalerMessage(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, address{[4]byte{1, 2, 3, 4}, [4]byte{5, 6, 7, 8}})
+	assert.Nil(t, err)
+
+	var restored address
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, address{[4]byte{1, 2, 3, 4}, [4]byte{5, 6, 7, 8}}, restored)
+}
@@ -0,0 +1,74 @@
+package moneroproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+)
+
+// isPodSlice reports whether t is a slice (or a pointer to a slice) of fixed-size values
+// which can be packed into a binary string
+func isPodSlice(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+
+	return binary.Size(reflect.Zero(t.Elem()).Interface()) > 0
+}
+
+// encodePodBlob writes a slice of fixed-size values as a single binary string. Values are packed
+// in little endian byte order without any padding
+func (e *encoder) encodePodBlob(value reflect.Value) error {
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	e.writeType(TypeBinaryString)
+	err := e.packVarint(uint64(binary.Size(value.Interface())))
+	if err == nil {
+		err = binary.Write(e, binary.LittleEndian, value.Interface())
+	}
+
+	if err != nil {
+		return e.error(err, value.Kind(), TypeBinaryString)
+	}
+
+	return nil
+}
+
+// decodePodBlob reads a binary string packed by encodePodBlob into a slice of fixed-size values
+func (d *decoder) decodePodBlob(value reflect.Value) error {
+	value = indirect(value)
+
+	t, err := d.readType()
+	if err != nil {
+		return d.error(err, value.Kind(), 0)
+	}
+
+	if t != TypeBinaryString {
+		return d.error(ErrUnexpectedType, value.Kind(), t)
+	}
+
+	data, err := d.readBinaryString()
+	if err != nil {
+		return d.error(err, value.Kind(), t)
+	}
+
+	elemSize := binary.Size(reflect.Zero(value.Type().Elem()).Interface())
+	if len(data)%elemSize != 0 {
+		return d.error(ErrLengthMismatch, value.Kind(), t)
+	}
+
+	slice := reflect.MakeSlice(value.Type(), len(data)/elemSize, len(data)/elemSize)
+	err = binary.Read(bytes.NewReader(data), binary.LittleEndian, slice.Interface())
+	if err != nil {
+		return d.error(err, value.Kind(), t)
+	}
+
+	value.Set(slice)
+	return nil
+}
@@ -2,11 +2,17 @@ package moneroproto
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestDecoder returns a decoder reading data
+func newTestDecoder(data []byte) *decoder {
+	return &NewDecoder(bytes.NewReader(data)).d
+}
+
 func TestPackVarint(t *testing.T) {
 	tests := []struct {
 		value    uint64
@@ -26,15 +32,15 @@ func TestPackVarint(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		buffer := bytes.Buffer{}
-		packVarint(&buffer, test.value)
-		assert.Equal(t, test.expected, buffer.Bytes(), "serialized values must be equal")
+		e := encoder{}
+		e.packVarint(test.value)
+		assert.Equal(t, test.expected, e.buf, "serialized values must be equal")
 	}
 }
 
 func TestPackVarintFail(t *testing.T) {
-	buffer := bytes.Buffer{}
-	_, err := packVarint(&buffer, 4611686018427387904)
+	e := encoder{}
+	err := e.packVarint(4611686018427387904)
 	assert.Error(t, err, "must return an error")
 }
 
@@ -57,8 +63,8 @@ func TestUnpackVarint(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		reader := bytes.NewReader(test.value)
-		actual, err := unpackVarint(reader)
+		d := newTestDecoder(test.value)
+		actual, err := d.unpackVarint()
 		assert.Nil(t, err)
 		assert.Equal(t, test.expected, actual, "deserialized values must be equal")
 	}
@@ -66,16 +72,16 @@ func TestUnpackVarint(t *testing.T) {
 
 func TestWriteUint64(t *testing.T) {
 	expected := []byte{0x05, 0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0xde}
-	buffer := bytes.Buffer{}
-	_, err := writeUint64(&buffer, 0xdeadbeefdeadbeef)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(uint64(0xdeadbeefdeadbeef)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadUint64(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0xde})
-	val, err := readUint64(reader)
+	d := newTestDecoder([]byte{0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0xde})
+	val, err := d.readUint64()
 
 	assert.Nil(t, err)
 	assert.Equal(t, uint64(0xdeadbeefdeadbeef), val)
@@ -84,16 +90,16 @@ func TestReadUint64(t *testing.T) {
 
 func TestWriteUint32(t *testing.T) {
 	expected := []byte{0x06, 0xbe, 0xba, 0xad, 0xab}
-	buffer := bytes.Buffer{}
-	_, err := writeUint32(&buffer, 0xabadbabe)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(uint32(0xabadbabe)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadUint32(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xbe, 0xba, 0xad, 0xab})
-	val, err := readUint32(reader)
+	d := newTestDecoder([]byte{0xbe, 0xba, 0xad, 0xab})
+	val, err := d.readUint32()
 
 	assert.Nil(t, err)
 	assert.Equal(t, uint32(0xabadbabe), val)
@@ -101,16 +107,16 @@ func TestReadUint32(t *testing.T) {
 
 func TestWriteUint32Short(t *testing.T) {
 	expected := []byte{0x06, 0xbe, 0xba, 0x00, 0x00}
-	buffer := bytes.Buffer{}
-	_, err := writeUint32(&buffer, 0xbabe)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(uint32(0xbabe)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadUint32Short(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xbe, 0xba, 0x00, 0x00})
-	val, err := readUint32(reader)
+	d := newTestDecoder([]byte{0xbe, 0xba, 0x00, 0x00})
+	val, err := d.readUint32()
 
 	assert.Nil(t, err)
 	assert.Equal(t, uint32(0xbabe), val)
@@ -118,16 +124,16 @@ func TestReadUint32Short(t *testing.T) {
 
 func TestWriteUint16(t *testing.T) {
 	expected := []byte{0x07, 0xad, 0xde}
-	buffer := bytes.Buffer{}
-	_, err := writeUint16(&buffer, 0xdead)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(uint16(0xdead)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadUint16(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xad, 0xde})
-	val, err := readUint16(reader)
+	d := newTestDecoder([]byte{0xad, 0xde})
+	val, err := d.readUint16()
 
 	assert.Nil(t, err)
 	assert.Equal(t, uint16(0xdead), val)
@@ -135,16 +141,16 @@ func TestReadUint16(t *testing.T) {
 
 func TestWriteUint8(t *testing.T) {
 	expected := []byte{0x08, 0xad}
-	buffer := bytes.Buffer{}
-	_, err := writeUint8(&buffer, 0xad)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(uint8(0xad)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadUint8(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xad})
-	val, err := readUint8(reader)
+	d := newTestDecoder([]byte{0xad})
+	val, err := d.readUint8()
 
 	assert.Nil(t, err)
 	assert.Equal(t, uint8(0xad), val)
@@ -152,16 +158,16 @@ func TestReadUint8(t *testing.T) {
 
 func TestWriteInt64(t *testing.T) {
 	expected := []byte{0x01, 0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0x7e}
-	buffer := bytes.Buffer{}
-	_, err := writeInt64(&buffer, 0x7eadbeefdeadbeef)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(int64(0x7eadbeefdeadbeef)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadInt64(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0x7e})
-	val, err := readInt64(reader)
+	d := newTestDecoder([]byte{0xef, 0xbe, 0xad, 0xde, 0xef, 0xbe, 0xad, 0x7e})
+	val, err := d.readInt64()
 
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0x7eadbeefdeadbeef), val)
@@ -169,16 +175,16 @@ func TestReadInt64(t *testing.T) {
 
 func TestWriteInt32(t *testing.T) {
 	expected := []byte{0x02, 0xbe, 0xba, 0xad, 0x7b}
-	buffer := bytes.Buffer{}
-	_, err := writeInt32(&buffer, 0x7badbabe)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(int32(0x7badbabe)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadInt32(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xbe, 0xba, 0xad, 0x7b})
-	val, err := readInt32(reader)
+	d := newTestDecoder([]byte{0xbe, 0xba, 0xad, 0x7b})
+	val, err := d.readInt32()
 
 	assert.Nil(t, err)
 	assert.Equal(t, int32(0x7badbabe), val)
@@ -186,16 +192,16 @@ func TestReadInt32(t *testing.T) {
 
 func TestWriteInt32Negative(t *testing.T) {
 	expected := []byte{0x02, 0xf0, 0xd8, 0xff, 0xff}
-	buffer := bytes.Buffer{}
-	_, err := writeInt32(&buffer, -10000)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(int32(-10000)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadInt32Negative(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xf0, 0xd8, 0xff, 0xff})
-	val, err := readInt32(reader)
+	d := newTestDecoder([]byte{0xf0, 0xd8, 0xff, 0xff})
+	val, err := d.readInt32()
 
 	assert.Nil(t, err)
 	assert.Equal(t, int32(-10000), val)
@@ -203,16 +209,16 @@ func TestReadInt32Negative(t *testing.T) {
 
 func TestWriteInt16(t *testing.T) {
 	expected := []byte{0x03, 0xad, 0x7e}
-	buffer := bytes.Buffer{}
-	_, err := writeInt16(&buffer, 0x7ead)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(int16(0x7ead)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadInt16(t *testing.T) {
-	reader := bytes.NewReader([]byte{0xad, 0x7e})
-	val, err := readInt16(reader)
+	d := newTestDecoder([]byte{0xad, 0x7e})
+	val, err := d.readInt16()
 
 	assert.Nil(t, err)
 	assert.Equal(t, int16(0x7ead), val)
@@ -220,16 +226,16 @@ func TestReadInt16(t *testing.T) {
 
 func TestWriteInt8(t *testing.T) {
 	expected := []byte{0x04, 0x7d}
-	buffer := bytes.Buffer{}
-	_, err := writeInt8(&buffer, 0x7d)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(int8(0x7d)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadInt8(t *testing.T) {
-	reader := bytes.NewReader([]byte{0x7d})
-	val, err := readInt8(reader)
+	d := newTestDecoder([]byte{0x7d})
+	val, err := d.readInt8()
 
 	assert.Nil(t, err)
 	assert.Equal(t, int8(0x7d), val)
@@ -237,16 +243,16 @@ func TestReadInt8(t *testing.T) {
 
 func TestWriteBoolTrue(t *testing.T) {
 	expected := []byte{0x0b, 0x01}
-	buffer := bytes.Buffer{}
-	_, err := writeBool(&buffer, true)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(true), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadBoolTrue(t *testing.T) {
-	reader := bytes.NewReader([]byte{0x01})
-	val, err := readBool(reader)
+	d := newTestDecoder([]byte{0x01})
+	val, err := d.readBool()
 
 	assert.Nil(t, err)
 	assert.Equal(t, true, val)
@@ -254,16 +260,16 @@ func TestReadBoolTrue(t *testing.T) {
 
 func TestWriteBoolFalse(t *testing.T) {
 	expected := []byte{0x0b, 0x00}
-	buffer := bytes.Buffer{}
-	_, err := writeBool(&buffer, false)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(false), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadBoolFalse(t *testing.T) {
-	reader := bytes.NewReader([]byte{0x0b, 0x00})
-	val, err := readBool(reader)
+	d := newTestDecoder([]byte{0x0b, 0x00})
+	val, err := d.readBool()
 
 	assert.Nil(t, err)
 	assert.Equal(t, false, val)
@@ -271,16 +277,16 @@ func TestReadBoolFalse(t *testing.T) {
 
 func TestWriteDouble(t *testing.T) {
 	expected := []byte{0x09, 0x2a, 0x80, 0x6f, 0xfc, 0x8c, 0x78, 0xe2, 0x3f}
-	buffer := bytes.Buffer{}
-	_, err := writeFloat64(&buffer, 0.5772156649)
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf(float64(0.5772156649)), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadDouble(t *testing.T) {
-	reader := bytes.NewReader([]byte{0x2a, 0x80, 0x6f, 0xfc, 0x8c, 0x78, 0xe2, 0x3f})
-	val, err := readFloat64(reader)
+	d := newTestDecoder([]byte{0x2a, 0x80, 0x6f, 0xfc, 0x8c, 0x78, 0xe2, 0x3f})
+	val, err := d.readFloat64()
 
 	assert.Nil(t, err)
 	assert.Equal(t, float64(0.5772156649), val)
@@ -288,16 +294,16 @@ func TestReadDouble(t *testing.T) {
 
 func TestWriteBinaryString(t *testing.T) {
 	expected := []byte{0x0a, 0x2c, 0x73, 0x61, 0x79, 0x20, 0x6d, 0x79, 0x20, 0x6e, 0x61, 0x6d, 0x65}
-	buffer := bytes.Buffer{}
-	_, err := writeBinaryString(&buffer, []byte("say my name"))
+	e := encoder{}
+	err := e.doEncode(reflect.ValueOf([]byte("say my name")), 1, 0)
 
 	assert.Nil(t, err)
-	assert.Equal(t, expected, buffer.Bytes())
+	assert.Equal(t, expected, e.buf)
 }
 
 func TestReadBinaryString(t *testing.T) {
-	reader := bytes.NewReader([]byte{0x2c, 0x73, 0x61, 0x79, 0x20, 0x6d, 0x79, 0x20, 0x6e, 0x61, 0x6d, 0x65})
-	val, err := readBinaryString(reader)
+	d := newTestDecoder([]byte{0x2c, 0x73, 0x61, 0x79, 0x20, 0x6d, 0x79, 0x20, 0x6e, 0x61, 0x6d, 0x65})
+	val, err := d.readBinaryString()
 
 	assert.Nil(t, err)
 	assert.Equal(t, []byte("say my name"), val)
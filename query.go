@@ -0,0 +1,493 @@
+package moneroproto
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The functions below look up a single value in an encoded message including the preamble without
+// decoding the rest of it. A path is a sequence of entry names and array indices written the way
+// SerializationError.Path is, e.g. "current_height", "blocks[3].block" or "output_indices[0].indices".
+// It may be split into several arguments, GetBytes(data, "blocks[3]", "block") is the same lookup.
+// Skipped values are walked over in place, so a lookup doesn't allocate unless it fails.
+// Missing entries and out of range indices are reported with ErrEntryNotFound
+
+// GetUint64 returns the integer at path, it may be of any integer wire type but must not be negative
+func GetUint64(data []byte, path ...string) (uint64, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return 0, err
+	}
+
+	val, negative, err := c.integer(t)
+	if err == nil && negative {
+		err = ErrValueOverflow
+	}
+
+	if err != nil {
+		return 0, c.error(err, path, t)
+	}
+
+	return val, nil
+}
+
+// GetInt64 returns the integer at path, it may be of any integer wire type but must fit int64
+func GetInt64(data []byte, path ...string) (int64, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return 0, err
+	}
+
+	val, negative, err := c.integer(t)
+	if err == nil && !negative && val > math.MaxInt64 {
+		err = ErrValueOverflow
+	}
+
+	if err != nil {
+		return 0, c.error(err, path, t)
+	}
+
+	return int64(val), nil
+}
+
+// GetFloat64 returns the double at path
+func GetFloat64(data []byte, path ...string) (float64, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return 0, err
+	}
+
+	buf, err := c.fixed(t, TypeDouble, 8)
+	if err != nil {
+		return 0, c.error(err, path, t)
+	}
+
+	return bytesToFloat64(buf), nil
+}
+
+// GetBool returns the bool at path
+func GetBool(data []byte, path ...string) (bool, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return false, err
+	}
+
+	buf, err := c.fixed(t, TypeBool, 1)
+	if err != nil {
+		return false, c.error(err, path, t)
+	}
+
+	return buf[0] == 1, nil
+}
+
+// GetBytes returns the binary string at path. The result aliases data
+func GetBytes(data []byte, path ...string) ([]byte, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if t != TypeBinaryString {
+		return nil, c.error(ErrUnexpectedType, path, t)
+	}
+
+	size, err := c.readVarint()
+	if err == nil {
+		err = c.need(size)
+	}
+
+	if err != nil {
+		return nil, c.error(err, path, t)
+	}
+
+	end := c.pos + int(size)
+	return data[c.pos:end:end], nil
+}
+
+// GetString returns the binary string at path as a string
+func GetString(data []byte, path ...string) (string, error) {
+	buf, err := GetBytes(data, path...)
+	return string(buf), err
+}
+
+// GetRaw returns the value at path along with its type tag, see RawEntry. The result aliases data
+// unless the value is an array element, which has no type tag of its own in the message
+func GetRaw(data []byte, path ...string) (RawEntry, error) {
+	c, t, err := lookup(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	start := c.pos
+	err = c.skipValue(t, 0)
+	if err != nil {
+		return nil, c.error(err, path, t)
+	}
+
+	if c.tagged {
+		return RawEntry(data[start-1 : c.pos : c.pos]), nil
+	}
+
+	return RawEntry(append([]byte{t}, data[start:c.pos]...)), nil
+}
+
+// cursor walks over an encoded message in memory
+type cursor struct {
+	data []byte
+	pos  int
+	// tagged is set if the type tag of the current value precedes it in data
+	tagged bool
+}
+
+// lookup finds the value at path in the message data. It returns the cursor positioned at
+// the value's payload and the value's wire type
+func lookup(data []byte, path []string) (cursor, byte, error) {
+	c := cursor{data: data}
+	if !bytes.HasPrefix(data, MessagePreamble) {
+		return c, 0, c.error(ErrPreambleMismatch, path, 0)
+	}
+	c.pos = len(MessagePreamble)
+
+	t := TypeObject
+	for _, elem := range path {
+		for len(elem) != 0 {
+			var err error
+			switch {
+			case elem[0] == '[':
+				end := strings.IndexByte(elem, ']')
+				if end < 0 {
+					return c, 0, c.error(ErrEntryNotFound, path, t)
+				}
+
+				var index uint64
+				index, err = strconv.ParseUint(elem[1:end], 10, 64)
+				if err == nil {
+					t, err = c.index(t, index)
+					c.tagged = false
+				} else {
+					err = ErrEntryNotFound
+				}
+				elem = elem[end+1:]
+			case elem[0] == '.':
+				elem = elem[1:]
+				continue
+			default:
+				end := strings.IndexAny(elem, ".[")
+				if end < 0 {
+					end = len(elem)
+				}
+
+				t, err = c.entry(t, elem[:end])
+				c.tagged = true
+				elem = elem[end:]
+			}
+
+			if err != nil {
+				return c, 0, c.error(err, path, t)
+			}
+		}
+	}
+
+	return c, t, nil
+}
+
+func (c *cursor) error(err error, path []string, wireType byte) error {
+	return &SerializationError{
+		Path:     strings.Join(path, "."),
+		Offset:   int64(c.pos),
+		Kind:     reflect.Invalid,
+		WireType: wireType,
+		Err:      err,
+	}
+}
+
+// entry moves the cursor from the start of a section to the value of its entry name
+func (c *cursor) entry(t byte, name string) (byte, error) {
+	if t != TypeObject {
+		return 0, ErrUnexpectedType
+	}
+
+	count, err := c.readVarint()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		entryName, err := c.readName()
+		if err != nil {
+			return 0, err
+		}
+
+		t, err := c.readByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if string(entryName) == name {
+			return t, nil
+		}
+
+		err = c.skipValue(t, 0)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, ErrEntryNotFound
+}
+
+// index moves the cursor from the start of an array to its element index
+func (c *cursor) index(t byte, index uint64) (byte, error) {
+	if t == TypeArray {
+		// an element of an array of arrays
+		var err error
+		t, err = c.readByte()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if t&FlagArray == 0 {
+		return 0, ErrUnexpectedType
+	}
+
+	count, err := c.readVarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if index >= count {
+		return 0, ErrEntryNotFound
+	}
+
+	elemType := t & ^FlagArray
+	for i := uint64(0); i < index; i++ {
+		err = c.skipValue(elemType, 0)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return elemType, nil
+}
+
+// readName reads an entry name, the result aliases the message
+func (c *cursor) readName() ([]byte, error) {
+	size, err := c.readByte()
+	if err == nil {
+		err = c.need(uint64(size))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	name := c.data[c.pos : c.pos+int(size)]
+	c.pos += int(size)
+	return name, nil
+}
+
+func (c *cursor) need(size uint64) error {
+	if size > uint64(len(c.data)-c.pos) {
+		return ErrUnexpectedEof
+	}
+
+	return nil
+}
+
+func (c *cursor) readByte() (byte, error) {
+	if c.pos >= len(c.data) {
+		return 0, ErrUnexpectedEof
+	}
+
+	b := c.data[c.pos]
+	c.pos++
+	return b, nil
+}
+
+func (c *cursor) readVarint() (uint64, error) {
+	if c.pos >= len(c.data) {
+		return 0, ErrUnexpectedEof
+	}
+
+	size := 1 << (c.data[c.pos] & MarkMask)
+	err := c.need(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+
+	val := uint64(0)
+	for i := size - 1; i >= 0; i-- {
+		val = val<<8 | uint64(c.data[c.pos+i])
+	}
+	c.pos += size
+
+	return val >> 2, nil
+}
+
+// fixed returns the payload of a fixed size value which is expected to be of wire type expected
+func (c *cursor) fixed(t byte, expected byte, size int) ([]byte, error) {
+	if t != expected {
+		return nil, ErrUnexpectedType
+	}
+
+	err := c.need(uint64(size))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := c.data[c.pos : c.pos+size]
+	c.pos += size
+	return buf, nil
+}
+
+// integer reads an integer of any integer wire type returning its absolute value and sign
+func (c *cursor) integer(t byte) (uint64, bool, error) {
+	switch t {
+	case TypeInt64:
+		buf, err := c.fixed(t, t, 8)
+		if err != nil {
+			return 0, false, err
+		}
+		return signed(bytesToInt64(buf))
+	case TypeInt32:
+		buf, err := c.fixed(t, t, 4)
+		if err != nil {
+			return 0, false, err
+		}
+		return signed(int64(bytesToInt32(buf)))
+	case TypeInt16:
+		buf, err := c.fixed(t, t, 2)
+		if err != nil {
+			return 0, false, err
+		}
+		return signed(int64(bytesToInt16(buf)))
+	case TypeInt8:
+		buf, err := c.fixed(t, t, 1)
+		if err != nil {
+			return 0, false, err
+		}
+		return signed(int64(int8(buf[0])))
+	case TypeUint64:
+		buf, err := c.fixed(t, t, 8)
+		if err != nil {
+			return 0, false, err
+		}
+		return bytesToUint64(buf), false, nil
+	case TypeUint32:
+		buf, err := c.fixed(t, t, 4)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(bytesToUint32(buf)), false, nil
+	case TypeUint16:
+		buf, err := c.fixed(t, t, 2)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(bytesToUint16(buf)), false, nil
+	case TypeUint8:
+		buf, err := c.fixed(t, t, 1)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(buf[0]), false, nil
+	}
+
+	return 0, false, ErrUnexpectedType
+}
+
+// signed returns the value as a two's complement uint64 along with its sign, so it's returned
+// unchanged by GetInt64
+func signed(val int64) (uint64, bool, error) {
+	return uint64(val), val < 0, nil
+}
+
+// skipValue moves the cursor past a value of wire type t, depth is the nesting level of the value
+func (c *cursor) skipValue(t byte, depth int) error {
+	if depth > DefaultLimits.MaxDepth {
+		return ErrDepthLimit
+	}
+
+	if t&FlagArray != 0 {
+		count, err := c.readVarint()
+		if err != nil {
+			return err
+		}
+
+		for i := uint64(0); i < count; i++ {
+			err = c.skipValue(t & ^FlagArray, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var size uint64
+	switch t {
+	case TypeInt64, TypeUint64, TypeDouble:
+		size = 8
+	case TypeInt32, TypeUint32:
+		size = 4
+	case TypeInt16, TypeUint16:
+		size = 2
+	case TypeInt8, TypeUint8, TypeBool:
+		size = 1
+	case TypeBinaryString:
+		var err error
+		size, err = c.readVarint()
+		if err != nil {
+			return err
+		}
+	case TypeObject:
+		count, err := c.readVarint()
+		if err != nil {
+			return err
+		}
+
+		for i := uint64(0); i < count; i++ {
+			_, err = c.readName()
+			if err != nil {
+				return err
+			}
+
+			t, err := c.readByte()
+			if err != nil {
+				return err
+			}
+
+			err = c.skipValue(t, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case TypeArray:
+		t, err := c.readByte()
+		if err != nil {
+			return err
+		}
+
+		if t&FlagArray == 0 {
+			return ErrUnexpectedType
+		}
+
+		return c.skipValue(t, depth+1)
+	default:
+		return ErrUnexpectedType
+	}
+
+	err := c.need(size)
+	if err != nil {
+		return err
+	}
+
+	c.pos += int(size)
+	return nil
+}
@@ -0,0 +1,123 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetValues(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	height, err := GetUint64(data, "current_height")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(445566), height)
+
+	tx, err := GetBytes(data, "blocks[1].txs[2]")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("Btx3"), tx)
+
+	index, err := GetInt64(data, "output_indices[1]", "indices[0].indices", "[2]")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), index)
+
+	status, err := GetString(data, "status")
+	assert.Nil(t, err)
+	assert.Equal(t, "hell!", status)
+
+	untrusted, err := GetBool(data, "untrusted")
+	assert.Nil(t, err)
+	assert.True(t, untrusted)
+}
+
+func TestGetRaw(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var obj RawGetBlocksFastResponse
+	err = Unmarshal(data, &obj)
+	assert.Nil(t, err)
+
+	blocks, err := GetRaw(data, "blocks")
+	assert.Nil(t, err)
+	assert.Equal(t, obj.Blocks, blocks)
+
+	// array elements get the type tag of the array
+	height, err := GetRaw(data, "output_indices[0].indices[1].indices[2]")
+	assert.Nil(t, err)
+	assert.Equal(t, RawEntry{TypeUint64, 8, 0, 0, 0, 0, 0, 0, 0}, height)
+}
+
+func TestGetErrors(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	_, err = GetUint64(data, "top_height")
+	assert.True(t, errors.Is(err, ErrEntryNotFound))
+
+	_, err = GetUint64(data, "blocks[2].block")
+	assert.True(t, errors.Is(err, ErrEntryNotFound))
+	assert.Equal(t, "blocks[2].block", err.(*SerializationError).Path)
+
+	_, err = GetUint64(data, "status")
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+
+	_, err = GetBytes(data[:len(data)-1], "top_hash")
+	assert.True(t, errors.Is(err, ErrUnexpectedEof))
+
+	_, err = GetUint64(data[1:], "current_height")
+	assert.True(t, errors.Is(err, ErrPreambleMismatch))
+
+	s := NewSection()
+	s.SetInt8("delta", -1)
+	s.SetUint64("height", 1<<63)
+	buffer := bytes.Buffer{}
+	err = WriteSection(&buffer, s)
+	assert.Nil(t, err)
+	data = buffer.Bytes()
+
+	_, err = GetUint64(data, "delta")
+	assert.True(t, errors.Is(err, ErrValueOverflow))
+
+	_, err = GetInt64(data, "height")
+	assert.True(t, errors.Is(err, ErrValueOverflow))
+
+	delta, err := GetInt64(data, "delta")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), delta)
+}
+
+func TestGetAllocations(t *testing.T) {
+	data, err := Marshal(makeLargeGetBlocksFastResponse())
+	assert.Nil(t, err)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_, err := GetUint64(data, "output_indices[999].indices[9].indices[1]")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	assert.Equal(t, float64(0), allocs)
+}
+
+func BenchmarkGetUint64(b *testing.B) {
+	data, err := Marshal(makeLargeGetBlocksFastResponse())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err = GetUint64(data, "current_height")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package moneroproto
+
+import (
+	"reflect"
+)
+
+// RawEntry is an encoded portable storage value: its type tag followed by the payload. It's like
+// json.RawMessage, a RawEntry field keeps the value as found in the message and is written back
+// verbatim, so parts of a message can be forwarded without decoding them.
+// Used as a whole message, RawEntry holds the root section prefixed with TypeObject
+type RawEntry []byte
+
+// RawField is a section entry kept undecoded. A struct field of type []RawField tagged
+// `monerobinkv:",unknown"` collects the entries the struct doesn't declare in wire order, they are
+// written back after the declared fields. Entries named like a declared field aren't written, the
+// field takes precedence. Strict decoding doesn't fail on entries collected this way
+type RawField struct {
+	Name  string
+	Value RawEntry
+}
+
+var (
+	rawEntryType  = reflect.TypeOf(RawEntry(nil))
+	rawFieldsType = reflect.TypeOf([]RawField(nil))
+)
+
+// Type returns the wire type of the entry
+func (r RawEntry) Type() byte {
+	if len(r) == 0 {
+		return 0
+	}
+
+	return r[0]
+}
+
+// encodeRaw writes raw as is. The root section has no type tag, so a message must be a raw object
+func (e *encoder) encodeRaw(raw RawEntry, level int) error {
+	if len(raw) == 0 {
+		return e.error(ErrNilElement, reflect.Slice, 0)
+	}
+
+	if level == 0 {
+		if raw.Type() != TypeObject {
+			return e.error(ErrUnexpectedType, reflect.Slice, raw.Type())
+		}
+
+		raw = raw[1:]
+	}
+
+	_, err := e.Write(raw)
+	return err
+}
+
+// undeclared returns the entries of fields the struct doesn't declare, fields is copied only if
+// some of them are declared
+func (c *structCodec) undeclared(fields []RawField) []RawField {
+	for i := range fields {
+		if _, ok := c.byName[fields[i].Name]; !ok {
+			continue
+		}
+
+		kept := append(make([]RawField, 0, len(fields)-1), fields[:i]...)
+		for _, f := range fields[i+1:] {
+			if _, ok := c.byName[f.Name]; !ok {
+				kept = append(kept, f)
+			}
+		}
+
+		return kept
+	}
+
+	return fields
+}
+
+// encodeUnknown writes the entries collected by the unknown field of a struct
+func (e *encoder) encodeUnknown(fields []RawField) error {
+	for _, f := range fields {
+		e.path.pushName(f.Name)
+		err := e.writeName(f.Name)
+		if err == nil {
+			err = e.encodeRaw(f.Value, 1)
+		}
+		if err == nil {
+			err = e.maybeFlush()
+		}
+
+		if err != nil {
+			return e.error(err, reflect.Slice, f.Value.Type())
+		}
+		e.path.pop()
+	}
+
+	return nil
+}
+
+// decodeUnknown reads the value of an entry named name and appends it to the unknown field v
+func (d *decoder) decodeUnknown(name string, v reflect.Value) error {
+	d.path.pushName(name)
+	field := RawField{Name: name}
+	err := d.decodeRaw(reflect.ValueOf(&field.Value).Elem())
+	if err != nil {
+		return err
+	}
+	d.path.pop()
+
+	v.Set(reflect.Append(v, reflect.ValueOf(field)))
+	return nil
+}
+
+// decodeRaw reads a tagged value into RawEntry v without interpreting it
+func (d *decoder) decodeRaw(v reflect.Value) error {
+	t, err := d.readType()
+	if err != nil {
+		return d.error(err, v.Kind(), 0)
+	}
+
+	return d.captureValue(t, v)
+}
+
+// captureValue skips a value of the wire type storing the bytes read along with the type tag into
+// RawEntry v. Values are validated the same way skipped fields are
+func (d *decoder) captureValue(valueType byte, v reflect.Value) error {
+	d.captured = append(make([]byte, 0, 64), valueType)
+	d.capturing = true
+	err := d.skipValue(valueType)
+	d.capturing = false
+
+	raw := d.captured
+	d.captured = nil
+	if err != nil {
+		return err
+	}
+
+	v.SetBytes(raw)
+	return nil
+}
+
+// capture appends bytes read while capturing a value, they are charged against the allocation budget
+func (d *decoder) capture(p []byte) error {
+	err := d.allocate(uint64(len(p)), 1)
+	if err != nil {
+		return err
+	}
+
+	d.captured = append(d.captured, p...)
+	return nil
+}
+
+// reserveCapture checks that a payload of size bytes fits the limits before it's read and captured,
+// blob is set if it's a binary string. The bytes are charged as they are read
+func (d *decoder) reserveCapture(size uint64, blob bool) error {
+	if blob && (size > maxDecodedLength || (d.limits.MaxBlobSize != 0 && size > d.limits.MaxBlobSize)) {
+		return ErrBlobTooLarge
+	}
+
+	if size > maxDecodedLength || (d.limits.MaxAllocation != 0 && size > d.limits.MaxAllocation-d.allocated) {
+		return ErrAllocationLimit
+	}
+
+	if free := cap(d.captured) - len(d.captured); uint64(free) < size {
+		d.captured = append(make([]byte, 0, len(d.captured)+int(size)), d.captured...)
+	}
+
+	return nil
+}
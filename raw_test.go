@@ -0,0 +1,208 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type RawGetBlocksFastResponse struct {
+	Blocks        RawEntry `monerobinkv:"blocks"`
+	StartHeight   uint64   `monerobinkv:"start_height"`
+	CurrentHeight uint64   `monerobinkv:"current_height"`
+	Status        string   `monerobinkv:"status"`
+	OutputIndices RawEntry `monerobinkv:"output_indices"`
+	Untrusted     bool     `monerobinkv:"untrusted"`
+	Credits       *uint64  `monerobinkv:"credits"`
+	TopHash       string   `monerobinkv:"top_hash"`
+}
+
+func TestRawEntryRoundTrip(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var raw RawGetBlocksFastResponse
+	err = Unmarshal(data, &raw)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedGetBlocksFastResponse.Status, raw.Status)
+	assert.Equal(t, expectedGetBlocksFastResponse.TopHash, raw.TopHash)
+	assert.Equal(t, TypeObject|FlagArray, raw.Blocks.Type())
+
+	blocks, err := Marshal(struct {
+		Blocks []BlockCompleteEntry `monerobinkv:"blocks"`
+	}{expectedGetBlocksFastResponse.Blocks})
+	assert.Nil(t, err)
+	// preamble, entry count, name length and name precede the value
+	assert.Equal(t, blocks[len(MessagePreamble)+1+1+len("blocks"):], []byte(raw.Blocks))
+
+	forwarded, err := Marshal(&raw)
+	assert.Nil(t, err)
+	assert.Equal(t, data, forwarded)
+}
+
+func TestRawEntryFromStream(t *testing.T) {
+	expected := makeLargeGetBlocksFastResponse()
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, expected)
+	assert.Nil(t, err)
+	data := append([]byte(nil), buffer.Bytes()...)
+
+	var raw RawGetBlocksFastResponse
+	err = Read(&countingReader{reader: &buffer}, &raw)
+	assert.Nil(t, err)
+
+	buffer.Reset()
+	err = Write(&buffer, &raw)
+	assert.Nil(t, err)
+	assert.Equal(t, data, buffer.Bytes())
+}
+
+func TestRawEntryMessage(t *testing.T) {
+	data, err := Marshal(&GetBlocksFastRequest{Client: "wallet", StartHeight: 100, Prune: true})
+	assert.Nil(t, err)
+
+	var raw RawEntry
+	err = Unmarshal(data, &raw)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeObject, raw.Type())
+	assert.Equal(t, data[len(MessagePreamble):], []byte(raw[1:]))
+
+	forwarded, err := Marshal(raw)
+	assert.Nil(t, err)
+	assert.Equal(t, data, forwarded)
+
+	_, err = Marshal(RawEntry{TypeUint8, 1})
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+}
+
+func TestRawEntryNil(t *testing.T) {
+	data, err := Marshal(RawGetBlocksFastResponse{Status: "OK"})
+	assert.Nil(t, err)
+
+	var obj GetBlocksFastResponse
+	err = Unmarshal(data, &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", obj.Status)
+	assert.Nil(t, obj.Blocks)
+}
+
+func TestRawEntryLimits(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var raw RawGetBlocksFastResponse
+	err = NewDecoder(bytes.NewReader(data), WithLimits(Limits{MaxAllocation: 16})).Decode(&raw)
+	assert.True(t, errors.Is(err, ErrAllocationLimit), "%v", err)
+
+	err = Unmarshal(data[:len(data)-40], &raw)
+	assert.True(t, errors.Is(err, ErrUnexpectedEof), "%v", err)
+}
+
+func TestRawEntryBlobLimits(t *testing.T) {
+	data, err := Marshal(&BlockCompleteEntry{Block: make([]byte, 1000)})
+	assert.Nil(t, err)
+
+	var raw struct {
+		Block RawEntry `monerobinkv:"block"`
+	}
+	err = ReadWithLimits(bytes.NewReader(data), &raw, Limits{MaxBlobSize: 10})
+	assert.True(t, errors.Is(err, ErrBlobTooLarge), "%v", err)
+	assert.Equal(t, "block", err.(*SerializationError).Path)
+
+	var unknown struct {
+		Unknown []RawField `monerobinkv:",unknown"`
+	}
+	err = ReadWithLimits(bytes.NewReader(data), &unknown, Limits{MaxBlobSize: 10})
+	assert.True(t, errors.Is(err, ErrBlobTooLarge), "%v", err)
+	assert.Equal(t, "block", err.(*SerializationError).Path)
+
+	// {"block": <1 GiB binary string>} is rejected before its payload is read
+	data = []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x0a,
+		0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}
+	err = ReadWithLimits(bytes.NewReader(data), &raw, Limits{MaxBlobSize: math.MaxUint64})
+	assert.True(t, errors.Is(err, ErrAllocationLimit), "%v", err)
+	assert.Equal(t, "block", err.(*SerializationError).Path)
+}
+
+type StatusOnlyResponse struct {
+	Status  string     `monerobinkv:"status"`
+	TopHash string     `monerobinkv:"top_hash"`
+	Unknown []RawField `monerobinkv:",unknown"`
+}
+
+type InvalidUnknownObject struct {
+	Unknown map[string]RawEntry `monerobinkv:",unknown"`
+}
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var obj StatusOnlyResponse
+	err = ReadStrict(bytes.NewReader(data), &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedGetBlocksFastResponse.Status, obj.Status)
+
+	names := make([]string, 0, len(obj.Unknown))
+	for _, f := range obj.Unknown {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"blocks", "start_height", "current_height", "output_indices", "untrusted"}, names)
+
+	forwarded, err := Marshal(&obj)
+	assert.Nil(t, err)
+
+	var resp GetBlocksFastResponse
+	err = Unmarshal(forwarded, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedGetBlocksFastResponse, resp)
+}
+
+func TestUnknownFieldsOnly(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var obj struct {
+		Unknown []RawField `monerobinkv:",unknown"`
+	}
+	err = Unmarshal(data, &obj)
+	assert.Nil(t, err)
+
+	forwarded, err := Marshal(&obj)
+	assert.Nil(t, err)
+	assert.Equal(t, data, forwarded)
+
+	// decoding again replaces collected entries instead of appending to them
+	count := len(obj.Unknown)
+	err = Unmarshal(data, &obj)
+	assert.Nil(t, err)
+	assert.Len(t, obj.Unknown, count)
+}
+
+func TestUnknownFieldsDeclaredName(t *testing.T) {
+	busy := RawEntry{TypeBinaryString, 0x10, 'B', 'U', 'S', 'Y'}
+	obj := StatusOnlyResponse{Status: "OK", Unknown: []RawField{{"status", busy}, {"height", RawEntry{TypeUint8, 5}}}}
+	data, err := Marshal(&obj)
+	assert.Nil(t, err)
+
+	var restored StatusOnlyResponse
+	err = Unmarshal(data, &restored)
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", restored.Status)
+	assert.Equal(t, []RawField{{"height", RawEntry{TypeUint8, 5}}}, restored.Unknown)
+	assert.Len(t, obj.Unknown, 2)
+}
+
+func TestUnknownFieldInvalidTag(t *testing.T) {
+	_, err := Marshal(InvalidUnknownObject{})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	_, err = Marshal(struct {
+		First  []RawField `monerobinkv:",unknown"`
+		Second []RawField `monerobinkv:",unknown"`
+	}{})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+}
@@ -0,0 +1,244 @@
+package moneroproto
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// byteReader is a source the decoder can read single bytes from without a call to the underlying stream
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// newByteReader wraps reader into a buffered reader unless it's able to serve single bytes itself,
+// like bytes.Reader, bytes.Buffer or bufio.Reader do
+func newByteReader(reader io.Reader) byteReader {
+	if r, ok := reader.(byteReader); ok {
+		return r
+	}
+
+	return bufio.NewReader(reader)
+}
+
+// exactReader serves single bytes of a stream which isn't an io.ByteReader without reading ahead,
+// so nothing past the end of a message is consumed. Bytes are read into the decoder's scratch space
+type exactReader struct {
+	io.Reader
+	scratch *[8]byte
+}
+
+// newExactReader wraps reader into an exactReader unless it's able to serve single bytes itself
+func newExactReader(reader io.Reader, scratch *[8]byte) byteReader {
+	if r, ok := reader.(byteReader); ok {
+		return r
+	}
+
+	return &exactReader{Reader: reader, scratch: scratch}
+}
+
+func (r *exactReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(r.Reader, r.scratch[:1])
+	return r.scratch[0], err
+}
+
+// sliceReader reads an in-memory message and is able to return its parts without copying
+type sliceReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.data[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// next returns the next size bytes of the message. The capacity of the returned slice is limited
+// so appending to it doesn't overwrite the rest of the message
+func (r *sliceReader) next(size uint64) ([]byte, error) {
+	if size > uint64(len(r.data)-r.pos) {
+		r.pos = len(r.data)
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	end := r.pos + int(size)
+	buf := r.data[r.pos:end:end]
+	r.pos = end
+	return buf, nil
+}
+
+// The methods below read primitive values using the decoder's scratch space, so reading a value
+// doesn't allocate
+
+func (d *decoder) ReadByte() (byte, error) {
+	b, err := d.reader.ReadByte()
+	if err == nil {
+		d.offset++
+		if d.capturing {
+			err = d.capture([]byte{b})
+		}
+	}
+
+	return b, err
+}
+
+// readFixed reads exactly n bytes, n <= 8. The returned slice is valid until the next read
+func (d *decoder) readFixed(n int) ([]byte, error) {
+	d.scratch = [8]byte{}
+	buf := d.scratch[:n]
+	_, err := io.ReadFull(d, buf)
+	return buf, err
+}
+
+func (d *decoder) readType() (byte, error) {
+	return d.ReadByte()
+}
+
+func (d *decoder) unpackVarint() (uint64, error) {
+	first, err := d.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	need := 1
+	switch first & MarkMask {
+	case MarkWord:
+		need = 2
+	case MarkDWord:
+		need = 4
+	case MarkInt64:
+		need = 8
+	}
+
+	d.scratch = [8]byte{first}
+	if need > 1 {
+		_, err = io.ReadFull(d, d.scratch[1:need])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return bytesToUint64(d.scratch[:]) >> 2, nil
+}
+
+func (d *decoder) readUint64() (uint64, error) {
+	buf, err := d.readFixed(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToUint64(buf), nil
+}
+
+func (d *decoder) readUint32() (uint32, error) {
+	buf, err := d.readFixed(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToUint32(buf), nil
+}
+
+func (d *decoder) readUint16() (uint16, error) {
+	buf, err := d.readFixed(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToUint16(buf), nil
+}
+
+func (d *decoder) readUint8() (uint8, error) {
+	return d.ReadByte()
+}
+
+func (d *decoder) readInt64() (int64, error) {
+	buf, err := d.readFixed(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToInt64(buf), nil
+}
+
+func (d *decoder) readInt32() (int32, error) {
+	buf, err := d.readFixed(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToInt32(buf), nil
+}
+
+func (d *decoder) readInt16() (int16, error) {
+	buf, err := d.readFixed(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToInt16(buf), nil
+}
+
+func (d *decoder) readInt8() (int8, error) {
+	b, err := d.ReadByte()
+	return int8(b), err
+}
+
+func (d *decoder) readFloat64() (float64, error) {
+	buf, err := d.readFixed(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToFloat64(buf), nil
+}
+
+func (d *decoder) readBool() (bool, error) {
+	b, err := d.ReadByte()
+	return b == 1, err
+}
+
+// readName reads a section entry name into the decoder's name buffer. The returned slice is valid
+// until the next readName
+func (d *decoder) readName() ([]byte, error) {
+	size, err := d.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.name[:size]
+	_, err = io.ReadFull(d, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return name, nil
+}
+
+func (d *decoder) skip(size uint64) error {
+	if r, ok := d.reader.(*bufio.Reader); ok && !d.capturing && size <= math.MaxInt32 {
+		n, err := r.Discard(int(size))
+		d.offset += int64(n)
+		return err
+	}
+
+	_, err := io.CopyN(ioutil.Discard, d, int64(size))
+	return err
+}
@@ -0,0 +1,231 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingReader hides io.ByteReader of the source like a network connection does and counts Read calls
+type countingReader struct {
+	reader io.Reader
+	reads  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.reader.Read(p)
+}
+
+// makeLargeGetBlocksFastResponse builds a response of about 4 MB similar to one of a busy chain
+func makeLargeGetBlocksFastResponse() GetBlocksFastResponse {
+	resp := GetBlocksFastResponse{
+		StartHeight:   2000000,
+		CurrentHeight: 2001000,
+		Status:        "OK",
+	}
+
+	for i := 0; i < 1000; i++ {
+		block := BlockCompleteEntry{Block: bytes.Repeat([]byte{byte(i)}, 1500)}
+		indices := BlockOutputIndices{}
+		for j := 0; j < 10; j++ {
+			block.Txs = append(block.Txs, bytes.Repeat([]byte{byte(j)}, 250))
+			indices.Indices = append(indices.Indices, TxOutputIndices{Indices: []uint64{uint64(i), uint64(j)}})
+		}
+
+		resp.Blocks = append(resp.Blocks, block)
+		resp.OutputIndices = append(resp.OutputIndices, indices)
+	}
+
+	return resp
+}
+
+func TestDecoderBuffersStream(t *testing.T) {
+	expected := makeLargeGetBlocksFastResponse()
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, expected)
+	assert.Nil(t, err)
+
+	size := buffer.Len()
+	reader := &countingReader{reader: &buffer}
+
+	var obj GetBlocksFastResponse
+	err = NewDecoder(reader).Decode(&obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+	assert.True(t, reader.reads < size/1000, "%d reads for %d bytes", reader.reads, size)
+}
+
+func TestDecoderReadsExactlyFromByteReader(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, SimpleObject{1})
+	assert.Nil(t, err)
+	buffer.WriteString("trailer")
+
+	var obj SimpleObject
+	err = Read(&buffer, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "trailer", buffer.String())
+}
+
+func TestReadConsumesExactlyOneMessage(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, SimpleObject{1})
+	assert.Nil(t, err)
+	err = Write(&buffer, SimpleObject{2})
+	assert.Nil(t, err)
+	buffer.WriteString("trailer")
+
+	// countingReader isn't an io.ByteReader
+	reader := &countingReader{reader: &buffer}
+
+	var obj SimpleObject
+	err = Read(reader, &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{1}, obj)
+
+	s, err := ReadSection(reader)
+	assert.Nil(t, err)
+	txs, err := s.GetUint64("txs")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), txs)
+
+	assert.Equal(t, "trailer", buffer.String())
+}
+
+func TestUnmarshalNoCopy(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	var obj GetBlocksFastResponse
+	err = UnmarshalNoCopy(data, &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedGetBlocksFastResponse, obj)
+
+	block := bytes.Index(data, []byte("AAAblockAAA"))
+	tx := bytes.Index(data, []byte("Btx2"))
+	status := bytes.Index(data, []byte("hell!"))
+	data[block] = 'Z'
+	data[tx] = 'Z'
+	data[status] = 'Z'
+
+	assert.Equal(t, []byte("ZAAblockAAA"), obj.Blocks[0].Block)
+	assert.Equal(t, []byte("Ztx2"), obj.Blocks[1].Txs[1])
+	assert.Equal(t, "hell!", obj.Status)
+	assert.Equal(t, len(obj.Blocks[0].Block), cap(obj.Blocks[0].Block))
+}
+
+func TestUnmarshalNoCopyTruncated(t *testing.T) {
+	data, err := Marshal(BlockCompleteEntry{Block: []byte("block")})
+	assert.Nil(t, err)
+
+	var obj BlockCompleteEntry
+	err = UnmarshalNoCopy(data[:len(data)-2], &obj)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedEof))
+}
+
+func BenchmarkUnmarshalGetBlocksFastResponse(b *testing.B) {
+	data, err := Marshal(makeLargeGetBlocksFastResponse())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var obj GetBlocksFastResponse
+		err = Unmarshal(data, &obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalNoCopyGetBlocksFastResponse(b *testing.B) {
+	data, err := Marshal(makeLargeGetBlocksFastResponse())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var obj GetBlocksFastResponse
+		err = UnmarshalNoCopy(data, &obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadGetBlocksFastResponse shows the cost of reading an unbuffered stream, see Read
+func BenchmarkReadGetBlocksFastResponse(b *testing.B) {
+	benchmarkReadStream(b, Read)
+}
+
+func BenchmarkDecoderGetBlocksFastResponse(b *testing.B) {
+	benchmarkReadStream(b, func(reader io.Reader, obj interface{}) error {
+		return NewDecoder(reader).Decode(obj)
+	})
+}
+
+// benchmarkReadStream decodes a large response with read from a stream which isn't an io.ByteReader
+func benchmarkReadStream(b *testing.B, read func(io.Reader, interface{}) error) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, makeLargeGetBlocksFastResponse())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	reads := 0
+	for i := 0; i < b.N; i++ {
+		reader := &countingReader{reader: bytes.NewReader(data)}
+
+		var obj GetBlocksFastResponse
+		err = read(reader, &obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		reads += reader.reads
+	}
+
+	b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+}
+
+func BenchmarkReadGetBlocksFastResponseBytes(b *testing.B) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, makeLargeGetBlocksFastResponse())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var obj GetBlocksFastResponse
+		err = Read(bytes.NewReader(data), &obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,51 +3,27 @@ package moneroproto
 import "github.com/exantech/moneroutil"
 
 type GetHashesFastRequest struct {
-	Client      []byte `monerobinkv:"client"`
-	BlockIds    []byte `monerobinkv:"block_ids"`
-	StartHeight uint64 `monerobinkv:"start_height"`
-}
-
-func (g *GetHashesFastRequest) SetHashes(hashes []moneroutil.Hash) {
-	g.BlockIds = HashesToByteSlice(hashes)
-}
-
-func (g *GetHashesFastRequest) GetHashes() (error, []moneroutil.Hash) {
-	return ByteSliceToHashes(g.BlockIds)
+	Client      string            `monerobinkv:"client,omitempty"`
+	BlockIds    []moneroutil.Hash `monerobinkv:"block_ids,podblob"`
+	StartHeight uint64            `monerobinkv:"start_height"`
 }
 
 type GetHashesFastResponse struct {
-	BlockIds      []byte `monerobinkv:"m_block_ids"`
-	StartHeight   uint64 `monerobinkv:"start_height"`
-	CurrentHeight uint64 `monerobinkv:"current_height"`
-	Status        []byte `monerobinkv:"status"`
-	Untrusted     bool   `monerobinkv:"untrusted"`
-	Credits       uint64 `monerobinkv:"credits"`
-	TopHash       []byte `monerobinkv:"top_hash"`
-}
-
-func (g *GetHashesFastResponse) SetHashes(hashes []moneroutil.Hash) {
-	g.BlockIds = HashesToByteSlice(hashes)
-}
-
-func (g *GetHashesFastResponse) GetHashes() (error, []moneroutil.Hash) {
-	return ByteSliceToHashes(g.BlockIds)
+	BlockIds      []moneroutil.Hash `monerobinkv:"m_block_ids,podblob"`
+	StartHeight   uint64            `monerobinkv:"start_height"`
+	CurrentHeight uint64            `monerobinkv:"current_height"`
+	Status        string            `monerobinkv:"status"`
+	Untrusted     bool              `monerobinkv:"untrusted"`
+	Credits       *uint64           `monerobinkv:"credits"`
+	TopHash       string            `monerobinkv:"top_hash"` // hex, empty unless the daemon has RPC payments enabled
 }
 
 type GetBlocksFastRequest struct {
-	Client      []byte `monerobinkv:"client"`
-	BlockIds    []byte `monerobinkv:"block_ids"`
-	StartHeight uint64 `monerobinkv:"start_height"`
-	Prune       bool   `monerobinkv:"prune"`
-	NoMinerTx   bool   `monerobinkv:"no_miner_tx"`
-}
-
-func (g *GetBlocksFastRequest) SetHashes(hashes []moneroutil.Hash) {
-	g.BlockIds = HashesToByteSlice(hashes)
-}
-
-func (g *GetBlocksFastRequest) GetHashes() (error, []moneroutil.Hash) {
-	return ByteSliceToHashes(g.BlockIds)
+	Client      string            `monerobinkv:"client,omitempty"`
+	BlockIds    []moneroutil.Hash `monerobinkv:"block_ids,podblob"`
+	StartHeight uint64            `monerobinkv:"start_height"`
+	Prune       bool              `monerobinkv:"prune"`
+	NoMinerTx   bool              `monerobinkv:"no_miner_tx"`
 }
 
 type BlockCompleteEntry struct {
@@ -69,9 +45,9 @@ type GetBlocksFastResponse struct {
 	Blocks        []BlockCompleteEntry `monerobinkv:"blocks"`
 	StartHeight   uint64               `monerobinkv:"start_height"`
 	CurrentHeight uint64               `monerobinkv:"current_height"`
-	Status        []byte               `monerobinkv:"status"`
+	Status        string               `monerobinkv:"status"`
 	OutputIndices []BlockOutputIndices `monerobinkv:"output_indices"`
 	Untrusted     bool                 `monerobinkv:"untrusted"`
-	Credits       uint64               `monerobinkv:"credits"`
-	TopHash       []byte               `monerobinkv:"top_hash"`
+	Credits       *uint64              `monerobinkv:"credits"`
+	TopHash       string               `monerobinkv:"top_hash"` // hex, empty unless the daemon has RPC payments enabled
 }
@@ -28,7 +28,7 @@ func TestGetHashesFastRequestEncode(t *testing.T) {
 	obj := GetHashesFastRequest {
 		StartHeight: uint64(0xdeadbeefdeadbabe),
 	}
-	obj.SetHashes([]moneroutil.Hash{hash1, hash2})
+	obj.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	buffer := bytes.Buffer{}
 	err := Write(&buffer, obj)
@@ -41,7 +41,7 @@ func TestGetHashesFastRequestDecode(t *testing.T) {
 	expected := GetHashesFastRequest{
 		StartHeight: uint64(0xdeadbeefdeadbabe),
 	}
-	expected.SetHashes([]moneroutil.Hash{hash1, hash2})
+	expected.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
 		0x5f, 0x69, 0x64, 0x73, 0x0a, 0x01, 0x01, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb,
@@ -62,10 +62,10 @@ func TestGetHashesFastResponseDecode(t *testing.T) {
 	expected := GetHashesFastResponse{
 		StartHeight: uint64(0xdeadbeefdeadbabe),
 		CurrentHeight: uint64(0xdeadbeefdeadbaff),
-		Status: []byte("coolio"),
+		Status: "coolio",
 		Untrusted: true,
 	}
-	expected.SetHashes([]moneroutil.Hash{hash1, hash2})
+	expected.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	// obtained from monero
 	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x14, 0x0e, 0x63, 0x75, 0x72, 0x72, 0x65,
@@ -93,10 +93,11 @@ func TestGetHashesFastResponseSerialization(t *testing.T) {
 	primary := GetHashesFastResponse{
 		StartHeight: uint64(0xdeadbeefdeadbabe),
 		CurrentHeight: uint64(0xdeadbeefdeadbaff),
-		Status: []byte("coolio"),
+		Status: "coolio",
 		Untrusted: true,
+		TopHash: "cafe",
 	}
-	primary.SetHashes([]moneroutil.Hash{hash1, hash2})
+	primary.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	buffer := bytes.Buffer{}
 	err := Write(&buffer, primary)
@@ -117,7 +118,7 @@ func TestGetBlocksFastRequestDecode(t *testing.T) {
 		Prune: true,
 		NoMinerTx: false,
 	}
-	expected.SetHashes([]moneroutil.Hash{hash1, hash2})
+	expected.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x10, 0x09, 0x62, 0x6c, 0x6f,
 		0x63, 0x6b, 0x5f, 0x69, 0x64, 0x73, 0x0a, 0x01, 0x01, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa,
@@ -141,7 +142,7 @@ func TestGetBlocksFastRequestSerialize(t *testing.T) {
 		Prune: true,
 		NoMinerTx: false,
 	}
-	expected.SetHashes([]moneroutil.Hash{hash1, hash2})
+	expected.BlockIds = []moneroutil.Hash{hash1, hash2}
 
 	buf := bytes.Buffer{}
 	err := Write(&buf, expected)
@@ -172,7 +173,7 @@ var expectedGetBlocksFastResponse = GetBlocksFastResponse {
 	},
 	StartHeight: 112233,
 	CurrentHeight: 445566,
-	Status: []byte("hell!"),
+	Status: "hell!",
 	Untrusted: true,
 	OutputIndices: []BlockOutputIndices {
 		BlockOutputIndices {
@@ -0,0 +1,794 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+)
+
+var (
+	ErrEntryNotFound = errors.New("entry not found")
+)
+
+// Entry is a single portable storage value along with its wire type. Value holds a Go value
+// corresponding to Type:
+//
+//	TypeInt64 .. TypeUint8 - int64, int32, int16, int8, uint64, uint32, uint16, uint8
+//	TypeDouble - float64
+//	TypeBinaryString - []byte
+//	TypeBool - bool
+//	TypeObject - *Section
+//	any type with FlagArray set - []Entry, every element having the type without the flag.
+//	Elements of an array of arrays (TypeArray | FlagArray) are array entries themselves
+type Entry struct {
+	Type  byte
+	Value interface{}
+}
+
+func Int64Entry(val int64) Entry {
+	return Entry{TypeInt64, val}
+}
+
+func Int32Entry(val int32) Entry {
+	return Entry{TypeInt32, val}
+}
+
+func Int16Entry(val int16) Entry {
+	return Entry{TypeInt16, val}
+}
+
+func Int8Entry(val int8) Entry {
+	return Entry{TypeInt8, val}
+}
+
+func Uint64Entry(val uint64) Entry {
+	return Entry{TypeUint64, val}
+}
+
+func Uint32Entry(val uint32) Entry {
+	return Entry{TypeUint32, val}
+}
+
+func Uint16Entry(val uint16) Entry {
+	return Entry{TypeUint16, val}
+}
+
+func Uint8Entry(val uint8) Entry {
+	return Entry{TypeUint8, val}
+}
+
+func DoubleEntry(val float64) Entry {
+	return Entry{TypeDouble, val}
+}
+
+func BinaryStringEntry(val []byte) Entry {
+	return Entry{TypeBinaryString, val}
+}
+
+func StringEntry(val string) Entry {
+	return Entry{TypeBinaryString, []byte(val)}
+}
+
+func BoolEntry(val bool) Entry {
+	return Entry{TypeBool, val}
+}
+
+func SectionEntry(val *Section) Entry {
+	return Entry{TypeObject, val}
+}
+
+// ArrayEntry creates an array of elemType values. Use TypeArray as elemType to create an array of arrays
+func ArrayEntry(elemType byte, elems ...Entry) Entry {
+	if elems == nil {
+		elems = []Entry{}
+	}
+
+	return Entry{elemType | FlagArray, elems}
+}
+
+func (e Entry) IsArray() bool {
+	return e.Type&FlagArray != 0
+}
+
+// ElemType returns type of array elements, e must be an array
+func (e Entry) ElemType() byte {
+	return e.Type & ^FlagArray
+}
+
+func (e Entry) Int64() (int64, error) {
+	val, ok := e.Value.(int64)
+	if !ok || e.Type != TypeInt64 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Int32() (int32, error) {
+	val, ok := e.Value.(int32)
+	if !ok || e.Type != TypeInt32 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Int16() (int16, error) {
+	val, ok := e.Value.(int16)
+	if !ok || e.Type != TypeInt16 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Int8() (int8, error) {
+	val, ok := e.Value.(int8)
+	if !ok || e.Type != TypeInt8 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Uint64() (uint64, error) {
+	val, ok := e.Value.(uint64)
+	if !ok || e.Type != TypeUint64 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Uint32() (uint32, error) {
+	val, ok := e.Value.(uint32)
+	if !ok || e.Type != TypeUint32 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Uint16() (uint16, error) {
+	val, ok := e.Value.(uint16)
+	if !ok || e.Type != TypeUint16 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Uint8() (uint8, error) {
+	val, ok := e.Value.(uint8)
+	if !ok || e.Type != TypeUint8 {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Double() (float64, error) {
+	val, ok := e.Value.(float64)
+	if !ok || e.Type != TypeDouble {
+		return 0, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) BinaryString() ([]byte, error) {
+	val, ok := e.Value.([]byte)
+	if !ok || e.Type != TypeBinaryString {
+		return nil, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) String() (string, error) {
+	val, err := e.BinaryString()
+	return string(val), err
+}
+
+func (e Entry) Bool() (bool, error) {
+	val, ok := e.Value.(bool)
+	if !ok || e.Type != TypeBool {
+		return false, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Section() (*Section, error) {
+	val, ok := e.Value.(*Section)
+	if !ok || val == nil || e.Type != TypeObject {
+		return nil, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+func (e Entry) Array() ([]Entry, error) {
+	val, ok := e.Value.([]Entry)
+	if !ok || !e.IsArray() {
+		return nil, ErrUnexpectedType
+	}
+	return val, nil
+}
+
+// Section is a schemaless portable storage section, an ordered list of named entries.
+// Entries read from a stream keep the wire order and are written back in the same order
+type Section struct {
+	names   []string
+	entries []Entry
+}
+
+func NewSection() *Section {
+	return &Section{}
+}
+
+func (s *Section) Len() int {
+	return len(s.entries)
+}
+
+// At returns name and entry at position i
+func (s *Section) At(i int) (string, Entry) {
+	return s.names[i], s.entries[i]
+}
+
+func (s *Section) Names() []string {
+	names := make([]string, len(s.names))
+	copy(names, s.names)
+	return names
+}
+
+func (s *Section) index(name string) int {
+	for i, n := range s.names {
+		if n == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Section) Has(name string) bool {
+	return s.index(name) >= 0
+}
+
+func (s *Section) Get(name string) (Entry, bool) {
+	i := s.index(name)
+	if i < 0 {
+		return Entry{}, false
+	}
+
+	return s.entries[i], true
+}
+
+// Set replaces the entry with the same name keeping its position or appends a new one
+func (s *Section) Set(name string, entry Entry) {
+	i := s.index(name)
+	if i >= 0 {
+		s.entries[i] = entry
+		return
+	}
+
+	s.names = append(s.names, name)
+	s.entries = append(s.entries, entry)
+}
+
+func (s *Section) Delete(name string) bool {
+	i := s.index(name)
+	if i < 0 {
+		return false
+	}
+
+	s.names = append(s.names[:i], s.names[i+1:]...)
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+	return true
+}
+
+func (s *Section) entry(name string) (Entry, error) {
+	entry, ok := s.Get(name)
+	if !ok {
+		return Entry{}, ErrEntryNotFound
+	}
+
+	return entry, nil
+}
+
+func (s *Section) GetInt64(name string) (int64, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Int64()
+}
+
+func (s *Section) GetInt32(name string) (int32, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Int32()
+}
+
+func (s *Section) GetInt16(name string) (int16, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Int16()
+}
+
+func (s *Section) GetInt8(name string) (int8, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Int8()
+}
+
+func (s *Section) GetUint64(name string) (uint64, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Uint64()
+}
+
+func (s *Section) GetUint32(name string) (uint32, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Uint32()
+}
+
+func (s *Section) GetUint16(name string) (uint16, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Uint16()
+}
+
+func (s *Section) GetUint8(name string) (uint8, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Uint8()
+}
+
+func (s *Section) GetDouble(name string) (float64, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Double()
+}
+
+func (s *Section) GetBinaryString(name string) ([]byte, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.BinaryString()
+}
+
+func (s *Section) GetString(name string) (string, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return "", err
+	}
+	return entry.String()
+}
+
+func (s *Section) GetBool(name string) (bool, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return false, err
+	}
+	return entry.Bool()
+}
+
+func (s *Section) GetSection(name string) (*Section, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Section()
+}
+
+func (s *Section) GetArray(name string) ([]Entry, error) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Array()
+}
+
+func (s *Section) SetInt64(name string, val int64) {
+	s.Set(name, Int64Entry(val))
+}
+
+func (s *Section) SetInt32(name string, val int32) {
+	s.Set(name, Int32Entry(val))
+}
+
+func (s *Section) SetInt16(name string, val int16) {
+	s.Set(name, Int16Entry(val))
+}
+
+func (s *Section) SetInt8(name string, val int8) {
+	s.Set(name, Int8Entry(val))
+}
+
+func (s *Section) SetUint64(name string, val uint64) {
+	s.Set(name, Uint64Entry(val))
+}
+
+func (s *Section) SetUint32(name string, val uint32) {
+	s.Set(name, Uint32Entry(val))
+}
+
+func (s *Section) SetUint16(name string, val uint16) {
+	s.Set(name, Uint16Entry(val))
+}
+
+func (s *Section) SetUint8(name string, val uint8) {
+	s.Set(name, Uint8Entry(val))
+}
+
+func (s *Section) SetDouble(name string, val float64) {
+	s.Set(name, DoubleEntry(val))
+}
+
+func (s *Section) SetBinaryString(name string, val []byte) {
+	s.Set(name, BinaryStringEntry(val))
+}
+
+func (s *Section) SetString(name string, val string) {
+	s.Set(name, StringEntry(val))
+}
+
+func (s *Section) SetBool(name string, val bool) {
+	s.Set(name, BoolEntry(val))
+}
+
+func (s *Section) SetSection(name string, val *Section) {
+	s.Set(name, SectionEntry(val))
+}
+
+func (s *Section) SetArray(name string, elemType byte, elems ...Entry) {
+	s.Set(name, ArrayEntry(elemType, elems...))
+}
+
+// WriteSection writes the section as a message including preamble
+func WriteSection(writer io.Writer, s *Section) error {
+	return NewEncoder(writer).EncodeSection(s)
+}
+
+// ReadSection reads a whole message into a section. Like Read it consumes exactly the message, so
+// a stream which isn't an io.ByteReader should be wrapped into a bufio.Reader
+func ReadSection(reader io.Reader) (*Section, error) {
+	return newMessageDecoder(reader).DecodeSection()
+}
+
+// MarshalSection converts a tagged struct or a map into a section
+func MarshalSection(obj interface{}) (*Section, error) {
+	buffer := bytes.Buffer{}
+	err := Encode(&buffer, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{reader: &buffer}
+	return d.readSection()
+}
+
+// UnmarshalSection fills a tagged struct or a map pointed by obj from the section
+func UnmarshalSection(s *Section, obj interface{}) error {
+	e := &encoder{}
+	err := e.writeSection(s, 0)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(obj)
+	d := &decoder{reader: bytes.NewReader(e.buf)}
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return d.error(ErrNotPointer, v.Kind(), 0)
+	}
+
+	return d.decodeRoot(v.Elem())
+}
+
+func (e *encoder) writeSection(s *Section, level int) error {
+	if s == nil {
+		return e.error(ErrUnexpectedType, reflect.Invalid, TypeObject)
+	}
+
+	if level != 0 {
+		e.writeType(TypeObject)
+	}
+
+	err := e.packVarint(uint64(s.Len()))
+	if err != nil {
+		return e.error(err, reflect.Invalid, TypeObject)
+	}
+
+	for i, name := range s.names {
+		e.path.pushName(name)
+		err = e.writeName(name)
+		if err != nil {
+			return e.error(err, reflect.Invalid, s.entries[i].Type)
+		}
+
+		err = e.writeEntry(s.entries[i], level+1)
+		if err != nil {
+			return err
+		}
+		e.path.pop()
+	}
+
+	return nil
+}
+
+// writeEntry writes entry along with its type tag
+func (e *encoder) writeEntry(entry Entry, level int) error {
+	if entry.Type == TypeObject {
+		s, err := entry.Section()
+		if err != nil {
+			return e.error(err, reflect.Invalid, entry.Type)
+		}
+
+		return e.writeSection(s, level)
+	}
+
+	e.writeType(entry.Type)
+	return e.writeEntryValue(entry)
+}
+
+// writeEntryValue writes entry without the type tag, the same way array elements are written
+func (e *encoder) writeEntryValue(entry Entry) error {
+	var err error
+	switch entry.Type {
+	case TypeInt64:
+		var val int64
+		if val, err = entry.Int64(); err == nil {
+			e.writeUint64Blob(uint64(val))
+		}
+	case TypeInt32:
+		var val int32
+		if val, err = entry.Int32(); err == nil {
+			e.writeUint32Blob(uint32(val))
+		}
+	case TypeInt16:
+		var val int16
+		if val, err = entry.Int16(); err == nil {
+			e.writeUint16Blob(uint16(val))
+		}
+	case TypeInt8:
+		var val int8
+		if val, err = entry.Int8(); err == nil {
+			e.writeUint8Blob(uint8(val))
+		}
+	case TypeUint64:
+		var val uint64
+		if val, err = entry.Uint64(); err == nil {
+			e.writeUint64Blob(val)
+		}
+	case TypeUint32:
+		var val uint32
+		if val, err = entry.Uint32(); err == nil {
+			e.writeUint32Blob(val)
+		}
+	case TypeUint16:
+		var val uint16
+		if val, err = entry.Uint16(); err == nil {
+			e.writeUint16Blob(val)
+		}
+	case TypeUint8:
+		var val uint8
+		if val, err = entry.Uint8(); err == nil {
+			e.writeUint8Blob(val)
+		}
+	case TypeDouble:
+		var val float64
+		if val, err = entry.Double(); err == nil {
+			e.writeFloat64Blob(val)
+		}
+	case TypeBinaryString:
+		var val []byte
+		if val, err = entry.BinaryString(); err == nil {
+			err = e.writeBinaryStringBlob(val)
+		}
+	case TypeBool:
+		var val bool
+		if val, err = entry.Bool(); err == nil {
+			e.writeBoolBlob(val)
+		}
+	case TypeObject:
+		var val *Section
+		if val, err = entry.Section(); err == nil {
+			return e.writeSection(val, 0)
+		}
+	default:
+		if !entry.IsArray() {
+			return e.error(ErrUnexpectedType, reflect.Invalid, entry.Type)
+		}
+
+		return e.writeArrayEntry(entry)
+	}
+
+	if err != nil {
+		return e.error(err, reflect.Invalid, entry.Type)
+	}
+
+	return nil
+}
+
+// writeArrayEntry writes array entry without the type tag
+func (e *encoder) writeArrayEntry(entry Entry) error {
+	elems, err := entry.Array()
+	if err != nil {
+		return e.error(err, reflect.Invalid, entry.Type)
+	}
+
+	err = e.packVarint(uint64(len(elems)))
+	if err != nil {
+		return e.error(err, reflect.Invalid, entry.Type)
+	}
+
+	elemType := entry.ElemType()
+	for i, elem := range elems {
+		e.path.pushIndex(i)
+		if elemType == TypeArray {
+			// elements of an array of arrays carry their own type
+			if !elem.IsArray() {
+				return e.error(ErrUnexpectedType, reflect.Invalid, elem.Type)
+			}
+
+			err = e.writeEntry(elem, 0)
+		} else {
+			if elem.Type != elemType {
+				return e.error(ErrUnexpectedType, reflect.Invalid, elem.Type)
+			}
+
+			err = e.writeEntryValue(elem)
+		}
+
+		if err != nil {
+			return err
+		}
+		e.path.pop()
+	}
+
+	return nil
+}
+
+func (d *decoder) readSection() (*Section, error) {
+	err := d.enter()
+	if err != nil {
+		return nil, d.error(err, reflect.Invalid, TypeObject)
+	}
+	defer d.leave()
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkSectionEntries(size)
+	}
+
+	if err != nil {
+		return nil, d.error(err, reflect.Invalid, TypeObject)
+	}
+
+	s := NewSection()
+	for i := uint64(0); i < size; i++ {
+		buf, err := d.readName()
+		if err != nil {
+			return nil, d.error(err, reflect.Invalid, TypeObject)
+		}
+
+		name := string(buf)
+		d.path.pushName(name)
+		t, err := d.readType()
+		if err != nil {
+			return nil, d.error(err, reflect.Invalid, 0)
+		}
+
+		entry, err := d.readEntry(t)
+		if err != nil {
+			return nil, err
+		}
+		d.path.pop()
+
+		s.names = append(s.names, name)
+		s.entries = append(s.entries, entry)
+	}
+
+	return s, nil
+}
+
+// readEntry reads a value of the given type. It's used both for section entries and array elements
+func (d *decoder) readEntry(valueType byte) (Entry, error) {
+	if valueType&FlagArray != 0 {
+		return d.readArrayEntry(valueType)
+	}
+
+	var val interface{}
+	var err error
+	switch valueType {
+	case TypeInt64:
+		val, err = d.readInt64()
+	case TypeInt32:
+		val, err = d.readInt32()
+	case TypeInt16:
+		val, err = d.readInt16()
+	case TypeInt8:
+		val, err = d.readInt8()
+	case TypeUint64:
+		val, err = d.readUint64()
+	case TypeUint32:
+		val, err = d.readUint32()
+	case TypeUint16:
+		val, err = d.readUint16()
+	case TypeUint8:
+		val, err = d.readUint8()
+	case TypeDouble:
+		val, err = d.readFloat64()
+	case TypeBinaryString:
+		val, err = d.readBinaryString()
+	case TypeBool:
+		val, err = d.readBool()
+	case TypeObject:
+		s, err := d.readSection()
+		if err != nil {
+			return Entry{}, err
+		}
+
+		return SectionEntry(s), nil
+	case TypeArray:
+		t, err := d.readType()
+		if err != nil {
+			return Entry{}, d.error(err, reflect.Invalid, valueType)
+		}
+
+		if t&FlagArray == 0 {
+			return Entry{}, d.error(ErrUnexpectedType, reflect.Invalid, t)
+		}
+
+		return d.readArrayEntry(t)
+	default:
+		return Entry{}, d.error(ErrUnexpectedType, reflect.Invalid, valueType)
+	}
+
+	if err != nil {
+		return Entry{}, d.error(err, reflect.Invalid, valueType)
+	}
+
+	return Entry{valueType, val}, nil
+}
+
+func (d *decoder) readArrayEntry(arrayType byte) (Entry, error) {
+	err := d.enter()
+	if err != nil {
+		return Entry{}, d.error(err, reflect.Invalid, arrayType)
+	}
+	defer d.leave()
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkArray(size, entrySize)
+	}
+
+	if err != nil {
+		return Entry{}, d.error(err, reflect.Invalid, arrayType)
+	}
+
+	elemType := arrayType & ^FlagArray
+	elems := make([]Entry, 0, size)
+	for i := 0; i < int(size); i++ {
+		d.path.pushIndex(i)
+		elem, err := d.readEntry(elemType)
+		if err != nil {
+			return Entry{}, err
+		}
+		d.path.pop()
+
+		elems = append(elems, elem)
+	}
+
+	return Entry{arrayType, elems}, nil
+}
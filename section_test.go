@@ -0,0 +1,141 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSectionRoundTrip(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, &expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	s, err := ReadSection(bytes.NewReader(buffer.Bytes()))
+	assert.Nil(t, err)
+
+	height, err := s.GetUint64("current_height")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(445566), height)
+
+	status, err := s.GetString("status")
+	assert.Nil(t, err)
+	assert.Equal(t, "hell!", status)
+
+	blocks, err := s.GetArray("blocks")
+	assert.Nil(t, err)
+	assert.Len(t, blocks, 2)
+
+	block, err := blocks[1].Section()
+	assert.Nil(t, err)
+
+	txs, err := block.GetArray("txs")
+	assert.Nil(t, err)
+	assert.Equal(t, BinaryStringEntry([]byte("Btx2")), txs[1])
+
+	restored := bytes.Buffer{}
+	err = WriteSection(&restored, s)
+
+	assert.Nil(t, err)
+	assert.Equal(t, buffer.Bytes(), restored.Bytes())
+}
+
+func TestSectionArrayOfArraysRoundTrip(t *testing.T) {
+	// {"aa": [[1], []], "txs": 2}
+	data := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x02, 0x61, 0x61, 0x8d, 0x08, 0x85, 0x04,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x88, 0x00, 0x03, 0x74, 0x78, 0x73, 0x05, 0x02, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00}
+
+	s, err := ReadSection(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	expected := NewSection()
+	expected.SetArray("aa", TypeArray, ArrayEntry(TypeUint64, Uint64Entry(1)), ArrayEntry(TypeUint8))
+	expected.SetUint64("txs", 2)
+	assert.Equal(t, expected, s)
+
+	buffer := bytes.Buffer{}
+	err = WriteSection(&buffer, expected)
+
+	assert.Nil(t, err)
+	assert.Equal(t, data, buffer.Bytes())
+}
+
+func TestSectionSetGet(t *testing.T) {
+	s := NewSection()
+	s.SetUint32("b", 1)
+	s.SetString("a", "x")
+	s.SetUint32("b", 2)
+
+	assert.Equal(t, []string{"b", "a"}, s.Names())
+
+	val, err := s.GetUint32("b")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), val)
+
+	_, err = s.GetUint64("b")
+	assert.Equal(t, ErrUnexpectedType, err)
+
+	assert.True(t, s.Delete("b"))
+	_, err = s.GetUint32("b")
+	assert.Equal(t, ErrEntryNotFound, err)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestWriteSectionTypeMismatch(t *testing.T) {
+	s := NewSection()
+	s.SetArray("array", TypeUint16, Uint16Entry(1), Uint32Entry(2))
+
+	buffer := bytes.Buffer{}
+	err := WriteSection(&buffer, s)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+	assert.Equal(t, "array[1]", err.(*SerializationError).Path)
+}
+
+func TestWriteNilSection(t *testing.T) {
+	s := NewSection()
+	s.SetSection("x", nil)
+
+	_, err := s.GetSection("x")
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+
+	buffer := bytes.Buffer{}
+	err = WriteSection(&buffer, s)
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+	assert.Equal(t, "x", err.(*SerializationError).Path)
+
+	err = WriteSection(&buffer, nil)
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+}
+
+func TestWriteSectionNameTooLong(t *testing.T) {
+	s := NewSection()
+	s.SetUint8(strings.Repeat("n", 256), 1)
+
+	buffer := bytes.Buffer{}
+	err := WriteSection(&buffer, s)
+
+	assert.True(t, errors.Is(err, ErrNameTooLong))
+}
+
+func TestSectionStructConversion(t *testing.T) {
+	s, err := MarshalSection(expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	s.SetUint64("start_height", 1)
+
+	var obj GetBlocksFastResponse
+	err = UnmarshalSection(s, &obj)
+	assert.Nil(t, err)
+
+	expected := expectedGetBlocksFastResponse
+	expected.StartHeight = 1
+	assert.Equal(t, expected.Blocks, obj.Blocks)
+	assert.Equal(t, expected.OutputIndices, obj.OutputIndices)
+	assert.Equal(t, expected.StartHeight, obj.StartHeight)
+	assert.Equal(t, expected.Status, obj.Status)
+}
@@ -2,555 +2,1025 @@ package moneroproto
 
 import (
 	"bytes"
-	"errors"
 	"io"
-	"log"
 	"reflect"
+	"sort"
 )
 
-//TODO: rename it to EncodeMessage
+// encoder keeps the state of a single Write or Encode call. Encoded data is appended to buf
+// and written out to writer, if there is one, by flush
+type encoder struct {
+	buf    []byte
+	writer io.Writer
+	// flushed is the number of bytes written out, start is the length of buf before the message
+	flushed int64
+	start   int
+	path    fieldPath
+	// producers are the sources of the root struct's arrays, see ProduceArray
+	producers []ArrayProducer
+}
+
+func (e *encoder) offset() int64 {
+	return e.flushed + int64(len(e.buf)-e.start)
+}
+
+func (e *encoder) error(err error, kind reflect.Kind, wireType byte) error {
+	return newSerializationError(err, e.path, e.offset(), kind, wireType)
+}
+
+// TODO: rename it to EncodeMessage
 func Write(writer io.Writer, obj interface{}) error {
-	_, err := writer.Write(MessagePreamble)
+	return NewEncoder(writer).Encode(obj)
+}
+
+func Encode(writer io.Writer, obj interface{}) error {
+	return NewEncoder(writer, WithoutPreamble()).Encode(obj)
+}
+
+// Marshal returns the message encoding of a tagged struct or a map including the preamble
+func Marshal(obj interface{}) ([]byte, error) {
+	return AppendMarshal(make([]byte, 0, 256), obj)
+}
+
+// AppendMarshal appends the message encoding of obj to dst and returns the extended buffer
+func AppendMarshal(dst []byte, obj interface{}) ([]byte, error) {
+	e := &encoder{buf: dst, start: len(dst)}
+	e.buf = append(e.buf, MessagePreamble...)
+
+	err := e.encode(obj)
 	if err != nil {
-		return err
+		return dst, err
 	}
 
-	return Encode(writer, obj)
+	return e.buf, nil
 }
 
-func Encode(writer io.Writer, obj interface{}) error {
+func (e *encoder) encode(obj interface{}) error {
 	v := reflect.ValueOf(obj)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	return doEncode(writer, v, 0)
+	return e.doEncode(v, 0, 0)
 }
 
-func doEncode(writer io.Writer, value reflect.Value, level int) error {
-	switch value.Kind() {
+// doEncode writes the value along with its type tag. wireType overrides the wire type of integer
+// values and integer slice elements, it's 0 if the type is derived from the Go type
+func (e *encoder) doEncode(value reflect.Value, level int, wireType byte) error {
+	if m, ok := marshalerOf(value); ok {
+		return e.encodeMarshaler(m, value.Kind(), level)
+	}
+
+	if value.IsValid() && value.Type() == rawEntryType {
+		return e.encodeRaw(value.Bytes(), level)
+	}
+
+	kind := value.Kind()
+	if level == 0 && kind != reflect.Ptr && kind != reflect.Struct && kind != reflect.Map {
+		// the root of a message is a section
+		return e.error(ErrUnsupportedType, kind, 0)
+	}
+
+	switch kind {
 	case reflect.Invalid:
-		return errors.New("invalid type")
-	case reflect.Bool:
-		_, err := writeBool(writer, value.Bool())
-		return err
-	case reflect.Int64:
-		_, err := writeInt64(writer, value.Int())
-		return err
-	case reflect.Int:
-		_, err := writeInt32(writer, int32(value.Int()))
-		return err
-	case reflect.Int32:
-		_, err := writeInt32(writer, int32(value.Int()))
-		return err
-	case reflect.Int16:
-		_, err := writeInt16(writer, int16(value.Int()))
-		return err
-	case reflect.Int8:
-		_, err := writeInt8(writer, int8(value.Int()))
-		return err
-	case reflect.Uint64:
-		_, err := writeUint64(writer, value.Uint())
-		return err
-	case reflect.Uint:
-		_, err := writeUint32(writer, uint32(value.Uint()))
-		return err
-	case reflect.Uint32:
-		_, err := writeUint32(writer, uint32(value.Uint()))
-		return err
-	case reflect.Uint16:
-		_, err := writeUint16(writer, uint16(value.Uint()))
-		return err
-	case reflect.Uint8:
-		_, err := writeUint8(writer, uint8(value.Uint()))
-		return err
-	case reflect.Float64:
-		_, err := writeFloat64(writer, value.Float())
-		return err
+		return e.error(ErrUnsupportedType, reflect.Invalid, 0)
 	case reflect.Ptr:
-		return doEncode(writer, value.Elem(), level)
+		return e.doEncode(value.Elem(), level, wireType)
 	case reflect.Slice:
-		return encodeArray(writer, value)
+		return e.encodeArray(value, wireType)
 	case reflect.Struct:
-		return encodeObject(writer, value, level)
-	default:
-		//currently unsupported types: Array, Chan, Func, Interface, Map, String, UnsafePointer
-		log.Fatal("unsupported type: ", value.Kind())
+		return e.encodeObject(value, level)
+	case reflect.Map:
+		return e.encodeMap(value, level)
 	}
 
-	return nil
+	valueType, err := getWireObjectType(value.Type())
+	if err != nil {
+		return e.error(err, value.Kind(), 0)
+	}
+
+	if wireType != 0 && isIntegerKind(value.Kind()) {
+		valueType = wireType
+	}
+
+	e.writeType(valueType)
+	return e.encodeArrayElement(value, wireType)
 }
 
-func encodeObject(writer io.Writer, value reflect.Value, level int) error {
+func (e *encoder) encodeObject(value reflect.Value, level int) error {
 	if level != 0 {
-		_, err := writeObjectTag(writer)
-		if err != nil {
-			return err
+		e.writeType(TypeObject)
+	}
+
+	codec, err := cachedStructCodec(value.Type())
+	if err != nil {
+		return e.error(err, reflect.Struct, TypeObject)
+	}
+
+	var unknown []RawField
+	if codec.unknown >= 0 {
+		unknown = codec.undeclared(value.Field(codec.unknown).Interface().([]RawField))
+	}
+
+	count := len(unknown)
+	for i := range codec.fields {
+		f := &codec.fields[i]
+		if _, ok := e.producerOf(f); ok || !f.skip(value.Field(f.index)) {
+			count++
 		}
 	}
 
-	fields := value.NumField()
-	_, err := packVarint(writer, uint64(fields))
+	err = e.packVarint(uint64(count))
 	if err != nil {
-		return err
+		return e.error(err, reflect.Struct, TypeObject)
 	}
 
-	for i := 0; i < fields; i++ {
-		name := value.Type().Field(i).Tag.Get("monerobinkv")
-		if len(name) == 0 {
+	for i := range codec.fields {
+		f := &codec.fields[i]
+		fieldValue := value.Field(f.index)
+		p, produced := e.producerOf(f)
+		if !produced && f.skip(fieldValue) {
 			continue
 		}
 
-		_, err := writeName(writer, []byte(name))
+		e.path.pushName(f.tag.name)
+		err = e.writeName(f.tag.name)
 		if err != nil {
-			return err
+			return e.error(err, fieldValue.Kind(), 0)
+		}
+
+		if produced {
+			err = e.encodeProduced(p, f, fieldValue.Type())
+		} else {
+			err = f.encode(e, fieldValue, level+1)
+		}
+		if err == nil {
+			err = e.maybeFlush()
+		}
+
+		if err != nil {
+			return e.error(err, fieldValue.Kind(), 0)
 		}
+		e.path.pop()
+	}
+
+	return e.encodeUnknown(unknown)
+}
+
+// encodeMap writes a map with string keys as a section. Keys are written in sorted order
+// the same way epee does since it keeps section entries in std::map
+func (e *encoder) encodeMap(value reflect.Value, level int) error {
+	if value.Type().Key().Kind() != reflect.String {
+		return e.error(ErrUnsupportedType, reflect.Map, TypeObject)
+	}
 
-		err = doEncode(writer, value.Field(i), level+1)
+	if level != 0 {
+		e.writeType(TypeObject)
+	}
+
+	keys := value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	written := keys[:0]
+	for _, key := range keys {
+		if !isNilPointer(value.MapIndex(key)) {
+			written = append(written, key)
+		}
+	}
+	keys = written
+
+	err := e.packVarint(uint64(len(keys)))
+	if err != nil {
+		return e.error(err, reflect.Map, TypeObject)
+	}
+
+	for _, key := range keys {
+		e.path.pushName(key.String())
+		err = e.writeName(key.String())
+		if err != nil {
+			return e.error(err, reflect.Map, TypeObject)
+		}
+
+		err = e.doEncode(value.MapIndex(key), level+1, 0)
 		if err != nil {
 			return err
 		}
+		e.path.pop()
 	}
 
 	return nil
 }
 
-func encodeArray(writer io.Writer, value reflect.Value) error {
-	if value.Kind() != reflect.Slice {
-		//programming error
-		//TODO: remove log.fatals with panics
-		panic("value must be a slice")
+func (e *encoder) encodeArray(value reflect.Value, wireType byte) error {
+	if isMarshalerType(value.Type().Elem()) {
+		return e.encodeMarshalerArray(value)
 	}
 
-	//TODO: replace errors.New with error objects
-	elemType := getWireObjectType(value)
-	if elemType == TypeUint8 {
+	elemType, err := getWireObjectType(value.Type().Elem())
+	if err != nil {
+		return e.error(err, value.Type().Elem().Kind(), 0)
+	}
+
+	if wireType != 0 && elemType != TypeArray {
+		elemType = wireType | FlagArray
+	} else if elemType == TypeUint8 {
 		// encode []byte as binary string
 		elemType = TypeBinaryString
 	} else {
 		elemType |= FlagArray
 	}
 
-	_, err := writeType(writer, elemType)
-	if err != nil {
-		return err
+	e.writeType(elemType)
+
+	if elemType == TypeBinaryString {
+		err = e.writeBinaryStringBlob(value.Bytes())
+		if err != nil {
+			return e.error(err, reflect.Slice, elemType)
+		}
+
+		return nil
 	}
 
-	_, err = packVarint(writer, uint64(value.Len()))
+	err = e.packVarint(uint64(value.Len()))
 	if err != nil {
-		return err
+		return e.error(err, reflect.Slice, elemType)
 	}
 
 	for i := 0; i < value.Len(); i++ {
-		elem := value.Index(i)
-		err = encodeArrayElement(writer, elem)
+		e.path.pushIndex(i)
+		err = e.encodeArrayElement(value.Index(i), wireType)
+		if err == nil {
+			err = e.maybeFlush()
+		}
+
 		if err != nil {
-			return err
+			return e.error(err, value.Type().Elem().Kind(), 0)
 		}
+		e.path.pop()
 	}
 
 	return nil
 }
 
-func encodeArrayElement(writer io.Writer, value reflect.Value) error {
-	//if value.Kind() == reflect.Slice {
-	//	panic("writing slice of slices is currently not supported")
-	//}
-
-	if value.Kind() == reflect.Ptr {
-		return encodeArrayElement(writer, value.Elem())
-	}
+// encodeArrayElement writes the value without its type tag, the way array elements are written
+func (e *encoder) encodeArrayElement(value reflect.Value, wireType byte) error {
+	if wireType != 0 && isIntegerKind(value.Kind()) {
+		err := writeIntegerBlob(e, value, wireType)
+		if err != nil {
+			return e.error(err, value.Kind(), wireType)
+		}
 
-	if value.Kind() == reflect.Struct {
-		return encodeObject(writer, value, 0)
+		return nil
 	}
 
+	var err error
 	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return e.error(ErrNilElement, value.Kind(), 0)
+		}
+		return e.encodeArrayElement(value.Elem(), wireType)
+	case reflect.Struct:
+		return e.encodeObject(value, 0)
+	case reflect.Map:
+		return e.encodeMap(value, 0)
 	case reflect.Bool:
-		_, err := writeBoolBlob(writer, value.Bool())
-		return err
+		e.writeBoolBlob(value.Bool())
 	case reflect.Int64:
-		_, err := writeInt64Blob(writer, value.Int())
-		return err
+		e.writeUint64Blob(uint64(value.Int()))
 	case reflect.Int:
-		_, err := writeInt32Blob(writer, int32(value.Int()))
-		return err
+		// int is written as int32, it's range checked since it may be wider
+		err = writeIntegerBlob(e, value, TypeInt32)
 	case reflect.Int32:
-		_, err := writeInt32Blob(writer, int32(value.Int()))
-		return err
+		e.writeUint32Blob(uint32(value.Int()))
 	case reflect.Int16:
-		_, err := writeInt16Blob(writer, int16(value.Int()))
-		return err
+		e.writeUint16Blob(uint16(value.Int()))
 	case reflect.Int8:
-		_, err := writeInt8Blob(writer, int8(value.Int()))
-		return err
+		e.writeUint8Blob(byte(value.Int()))
 	case reflect.Uint64:
-		_, err := writeUint64Blob(writer, value.Uint())
-		return err
+		e.writeUint64Blob(value.Uint())
 	case reflect.Uint:
-		_, err := writeUint32Blob(writer, uint32(value.Uint()))
-		return err
+		err = writeIntegerBlob(e, value, TypeUint32)
 	case reflect.Uint32:
-		_, err := writeUint32Blob(writer, uint32(value.Uint()))
-		return err
+		e.writeUint32Blob(uint32(value.Uint()))
 	case reflect.Uint16:
-		_, err := writeUint16Blob(writer, uint16(value.Uint()))
-		return err
+		e.writeUint16Blob(uint16(value.Uint()))
 	case reflect.Uint8:
-		_, err := writeUint8Blob(writer, uint8(value.Uint()))
-		return err
+		e.writeUint8Blob(byte(value.Uint()))
 	case reflect.Float64:
-		_, err := writeFloat64Blob(writer, value.Float())
-		return err
+		e.writeFloat64Blob(value.Float())
+	case reflect.String:
+		err = e.writeStringBlob(value.String())
 	case reflect.Slice:
-		_, err := packVarint(writer, uint64(value.Len()))
-		if err != nil {
-			return err
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			// an element of an array of arrays is an array with its own type tag
+			return e.encodeArray(value, wireType)
+		}
+
+		err = e.writeBinaryStringBlob(value.Bytes())
+	case reflect.Array:
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			return e.error(ErrUnsupportedType, value.Kind(), 0)
 		}
-		_, err = writeBlob(writer, value.Bytes())
-		return err
+
+		err = e.writeBinaryStringBlob(byteArrayBytes(value))
 	default:
-		panic("unsupported array element type")
+		return e.error(ErrUnsupportedType, value.Kind(), 0)
+	}
+
+	if err != nil {
+		wireType, _ := getWireObjectType(value.Type())
+		return e.error(err, value.Kind(), wireType)
 	}
+
+	return nil
 }
 
-func getWireObjectType(value reflect.Value) byte {
-	var elem reflect.Value
-	if value.Kind() == reflect.Slice {
-		if value.Len() > 0 {
-			elem = value.Index(0)
-		} else {
-			slice := reflect.MakeSlice(value.Type(), 1, 1)
-			elem = slice.Index(0)
-		}
-	} else if value.Kind() == reflect.Ptr {
-		elem = value.Elem()
-	} else {
-		//programming error
-		panic("value must be a slice or a pointer")
+// getWireObjectType returns the wire type of Go type t
+func getWireObjectType(t reflect.Type) (byte, error) {
+	if t == rawEntryType {
+		// the wire type is known only from the value
+		return 0, ErrUnsupportedType
 	}
 
-	switch elem.Kind() {
+	switch t.Kind() {
 	case reflect.Bool:
-		return TypeBool
+		return TypeBool, nil
 	case reflect.Int64:
-		return TypeInt64
+		return TypeInt64, nil
 	case reflect.Int:
-		return TypeInt32
+		return TypeInt32, nil
 	case reflect.Int32:
-		return TypeInt32
+		return TypeInt32, nil
 	case reflect.Int16:
-		return TypeInt16
+		return TypeInt16, nil
 	case reflect.Int8:
-		return TypeInt8
+		return TypeInt8, nil
 	case reflect.Uint64:
-		return TypeUint64
+		return TypeUint64, nil
 	case reflect.Uint:
-		return TypeUint32
+		return TypeUint32, nil
 	case reflect.Uint32:
-		return TypeUint32
+		return TypeUint32, nil
 	case reflect.Uint16:
-		return TypeUint16
+		return TypeUint16, nil
 	case reflect.Uint8:
-		return TypeUint8
+		return TypeUint8, nil
 	case reflect.Float64:
-		return TypeDouble
+		return TypeDouble, nil
 	case reflect.Ptr:
-		return getWireObjectType(elem)
+		return getWireObjectType(t.Elem())
+	case reflect.String:
+		return TypeBinaryString, nil
 	case reflect.Slice:
-		//TODO: make recursive check if elem is of type []byte
-		return TypeBinaryString
-	case reflect.Struct:
-		return TypeObject
+		if t.Elem().Kind() != reflect.Uint8 {
+			// Marshaler elements choose their wire type themselves, it's known only after marshaling
+			if !isMarshalerType(t.Elem()) {
+				_, err := getWireObjectType(t.Elem())
+				if err != nil {
+					return 0, err
+				}
+			}
+			return TypeArray, nil
+		}
+		return TypeBinaryString, nil
+	case reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return 0, ErrUnsupportedType
+		}
+		return TypeBinaryString, nil
+	case reflect.Struct, reflect.Map:
+		return TypeObject, nil
 	default:
-		log.Fatal("unsupported type: ", value.Kind())
+		return 0, ErrUnsupportedType
 	}
+}
 
-	//shouldn't be reached
-	return 0
+// decoder keeps the state of a single Read call
+type decoder struct {
+	reader byteReader
+	offset int64
+	path   fieldPath
+	// strict makes decoder fail on fields not declared in the target struct instead of skipping them
+	strict bool
+	// noCopy makes binary strings alias the message, the reader must be a sliceReader then
+	noCopy bool
+	// lenientNumbers makes decoder convert integers between wire types and Go kinds, see LenientNumbers
+	lenientNumbers bool
+	limits         Limits
+	// streams are handlers of the root struct's arrays, see StreamArray
+	streams map[string]ElementHandler
+	// depth and allocated track resources spent so far, see Limits
+	depth     int
+	allocated uint64
+	// scratch space for primitive values and entry names
+	scratch [8]byte
+	name    [255]byte
+	// captured collects the bytes read while capturing is set, see RawEntry
+	captured  []byte
+	capturing bool
 }
 
-//TODO: rename it to DecodeMessage
-func Read(reader io.Reader, obj interface{}) error {
-	v := reflect.ValueOf(obj)
-	if v.Kind() != reflect.Ptr {
-		return errors.New("object is expected to be a pointer")
+func (d *decoder) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	d.offset += int64(n)
+	if d.capturing && n != 0 {
+		captureErr := d.capture(p[:n])
+		if captureErr != nil {
+			err = captureErr
+		}
 	}
+	return n, err
+}
 
-	if v.IsNil() {
-		return errors.New("nil pointer passed")
+func (d *decoder) error(err error, kind reflect.Kind, wireType byte) error {
+	return newSerializationError(eofError(err), d.path, d.offset, kind, wireType)
+}
+
+// TODO: rename it to DecodeMessage
+// Read reads exactly one message from the stream, the data following it is left unread. A stream
+// which isn't an io.ByteReader is read without buffering to achieve that, with a Read call for every
+// type tag, varint and entry name. That's slow for network streams like http.Response.Body, pass
+// them wrapped into a bufio.Reader, which keeps the data following the message, or use a Decoder
+func Read(reader io.Reader, obj interface{}) error {
+	return newMessageDecoder(reader).Decode(obj)
+}
+
+// ReadStrict works like Read but fails with ErrUnknownField if the message contains a field
+// the target struct doesn't declare
+func ReadStrict(reader io.Reader, obj interface{}) error {
+	return newMessageDecoder(reader, Strict()).Decode(obj)
+}
+
+// ReadWithLimits works like Read but applies the given limits instead of DefaultLimits, see WithLimits
+func ReadWithLimits(reader io.Reader, obj interface{}, limits Limits) error {
+	return newMessageDecoder(reader, WithLimits(limits)).Decode(obj)
+}
+
+// Unmarshal decodes a message including the preamble from data into a tagged struct or a map pointed by obj
+func Unmarshal(data []byte, obj interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(obj)
+}
+
+// UnmarshalNoCopy works like Unmarshal but []byte values of obj alias data instead of being copied,
+// so data must not be modified while obj is in use. Strings and byte arrays are still copied
+func UnmarshalNoCopy(data []byte, obj interface{}) error {
+	dec := NewDecoder(&sliceReader{data: data})
+	dec.d.noCopy = true
+	return dec.Decode(obj)
+}
+
+// decodeRoot reads the root section of a message into v
+func (d *decoder) decodeRoot(v reflect.Value) error {
+	if u, ok := unmarshalerOf(v); ok {
+		return d.decodeUnmarshaler(u, TypeObject, v.Kind())
 	}
 
-	preamble := make([]byte, len(MessagePreamble))
-	_, err := io.ReadFull(reader, preamble)
-	if err == io.EOF {
-		return ErrUnexpectedEof
+	if v.Type() == rawEntryType {
+		return d.captureValue(TypeObject, v)
 	}
 
+	return d.decodeObject(v)
+}
+
+func (d *decoder) readPreamble() error {
+	preamble := make([]byte, len(MessagePreamble))
+	_, err := io.ReadFull(d, preamble)
 	if err != nil {
-		return err
+		return d.error(err, reflect.Invalid, 0)
 	}
 
 	if !bytes.Equal(preamble, MessagePreamble) {
-		return errors.New("message preamble mismatch")
+		return d.error(ErrPreambleMismatch, reflect.Invalid, 0)
 	}
 
-	return decodeObject(reader, v.Elem())
+	return nil
 }
 
-func decodeObject(reader io.Reader, v reflect.Value) error {
+func (d *decoder) decodeObject(v reflect.Value) error {
+	err := d.enter()
+	if err != nil {
+		return d.error(err, v.Kind(), TypeObject)
+	}
+	defer d.leave()
+
+	if v.Kind() == reflect.Map {
+		return d.decodeMap(v)
+	}
+
 	if v.Kind() != reflect.Struct {
-		return errors.New("value is not a struct")
+		return d.error(ErrUnexpectedType, v.Kind(), TypeObject)
 	}
 
-	fields := structFields(v)
-	size, err := unpackVarint(reader)
-	if err == io.EOF {
-		return ErrUnexpectedEof
+	codec, err := cachedStructCodec(v.Type())
+	if err != nil {
+		return d.error(err, reflect.Struct, TypeObject)
+	}
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkSectionEntries(size)
 	}
 
 	if err != nil {
-		return err
+		return d.error(err, reflect.Struct, TypeObject)
+	}
+
+	if codec.unknown >= 0 {
+		v.Field(codec.unknown).Set(reflect.Zero(rawFieldsType))
 	}
 
 	for i := uint64(0); i < size; i++ {
-		name, err := readName(reader)
-		if err == io.EOF {
-			return ErrUnexpectedEof
+		name, err := d.readName()
+		if err != nil {
+			return d.error(err, reflect.Struct, TypeObject)
 		}
 
-		if err != nil {
-			return err
+		f, ok := codec.field(name)
+		if !ok && codec.unknown >= 0 {
+			err = d.decodeUnknown(string(name), v.Field(codec.unknown))
+			if err != nil {
+				return err
+			}
+			continue
 		}
 
-		f, ok := fields[string(name)]
 		if !ok {
-			return errors.New("unexpected field name")
+			d.path.pushName(string(name))
+			if d.strict {
+				return d.error(ErrUnknownField, reflect.Struct, TypeObject)
+			}
+
+			t, err := d.readType()
+			if err != nil {
+				return d.error(err, reflect.Invalid, 0)
+			}
+
+			err = d.skipValue(t)
+			if err != nil {
+				return err
+			}
+			d.path.pop()
+			continue
 		}
 
-		if f.Kind() == reflect.Ptr {
-			f = f.Elem()
+		d.path.pushName(f.tag.name)
+		if handler, ok := d.streamHandler(f); ok {
+			err = d.decodeStream(handler, f, v.Field(f.index).Type())
+		} else {
+			err = f.decode(d, v.Field(f.index))
 		}
+		if err != nil {
+			return err
+		}
+		d.path.pop()
+	}
+
+	return nil
+}
+
+// decodeMap reads a section into a map with string keys. Every entry of the section
+// must be decodable into the map's element type
+func (d *decoder) decodeMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return d.error(ErrUnsupportedType, reflect.Map, TypeObject)
+	}
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkSectionEntries(size)
+	}
+
+	if err == nil {
+		err = d.allocate(size, uint64(v.Type().Elem().Size()))
+	}
+
+	if err != nil {
+		return d.error(err, reflect.Map, TypeObject)
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), int(size)))
+	}
 
-		err = doDecode(reader, f)
-		if err == io.EOF && i < size - 1 {
-			return ErrUnexpectedEof
+	for i := uint64(0); i < size; i++ {
+		name, err := d.readName()
+		if err != nil {
+			return d.error(err, reflect.Map, TypeObject)
 		}
 
+		key := string(name)
+		d.path.pushName(key)
+		elem := reflect.New(v.Type().Elem()).Elem()
+		err = d.doDecode(elem, 0)
 		if err != nil {
 			return err
 		}
+		d.path.pop()
+
+		v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
 	}
 
 	return nil
 }
 
-func doDecode(reader io.Reader, v reflect.Value) error {
-	t, err := readType(reader)
-	if err == io.EOF {
-		return ErrUnexpectedEof
+// doDecode reads a tagged value into v. wireType is the wire type override of the field, see fieldTag
+func (d *decoder) doDecode(v reflect.Value, wireType byte) error {
+	v = indirect(v)
+	if v.Type() == rawEntryType {
+		return d.decodeRaw(v)
 	}
 
+	t, err := d.readType()
 	if err != nil {
-		return err
+		return d.error(err, v.Kind(), 0)
+	}
+
+	if u, ok := unmarshalerOf(v); ok {
+		return d.decodeUnmarshaler(u, t, v.Kind())
 	}
 
-	//TODO: check type
 	if t&FlagArray != 0 {
 		if v.Kind() != reflect.Slice {
-			return errors.New("unexpected array occured")
+			return d.error(ErrUnexpectedType, v.Kind(), t)
 		}
 
-		return decodeArray(reader, t, v)
+		return d.decodeArray(t, v, wireType)
 	}
 
-	return decodeValue(reader, t, v)
+	return d.decodeValue(t, v, wireType)
 }
 
-func decodeValue(reader io.Reader, valueType byte, v reflect.Value) error {
+func (d *decoder) decodeValue(valueType byte, v reflect.Value, wireType byte) error {
+	v = indirect(v)
+	if u, ok := unmarshalerOf(v); ok {
+		return d.decodeUnmarshaler(u, valueType, v.Kind())
+	}
+
+	if v.Type() == rawEntryType {
+		// array elements have no type tags of their own
+		return d.error(ErrUnsupportedType, v.Kind(), valueType)
+	}
+
+	return d.decodeBuiltin(valueType, v, wireType)
+}
+
+// decodeBuiltin reads a value of the wire type into v by reflection, v must not be a pointer
+func (d *decoder) decodeBuiltin(valueType byte, v reflect.Value, wireType byte) error {
+	if wireType != 0 && valueType == wireType && isIntegerKind(v.Kind()) {
+		err := readInteger(d, v, valueType)
+		if err != nil {
+			return d.error(err, v.Kind(), valueType)
+		}
+
+		return nil
+	}
+
+	if d.lenientNumbers && isCoercible(valueType, v.Kind()) {
+		err := d.decodeCoerced(valueType, v)
+		if err != nil {
+			return d.error(err, v.Kind(), valueType)
+		}
+
+		return nil
+	}
+
 	var err error
 	switch valueType {
 	case TypeInt64:
 		if v.Kind() != reflect.Int64 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val int64
-		val, err = readInt64(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readInt64()
 		v.SetInt(val)
 	case TypeInt32:
 		if v.Kind() != reflect.Int32 && v.Kind() != reflect.Int {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val int32
-		val, err = readInt32(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readInt32()
 		v.SetInt(int64(val))
 	case TypeInt16:
 		if v.Kind() != reflect.Int16 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val int16
-		val, err = readInt16(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readInt16()
 		v.SetInt(int64(val))
 	case TypeInt8:
 		if v.Kind() != reflect.Int8 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val int8
-		val, err = readInt8(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readInt8()
 		v.SetInt(int64(val))
 	case TypeUint64:
 		if v.Kind() != reflect.Uint64 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val uint64
-		val, err = readUint64(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readUint64()
 		v.SetUint(val)
 	case TypeUint32:
 		if v.Kind() != reflect.Uint32 && v.Kind() != reflect.Uint {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val uint32
-		val, err = readUint32(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readUint32()
 		v.SetUint(uint64(val))
 	case TypeUint16:
 		if v.Kind() != reflect.Uint16 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val uint16
-		val, err = readUint16(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readUint16()
 		v.SetUint(uint64(val))
 	case TypeUint8:
 		if v.Kind() != reflect.Uint8 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val uint8
-		val, err = readUint8(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readUint8()
 		v.SetUint(uint64(val))
 	case TypeDouble:
 		if v.Kind() != reflect.Float64 {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val float64
-		val, err = readFloat64(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readFloat64()
 		v.SetFloat(val)
 	case TypeBinaryString:
-		if v.Kind() != reflect.Slice {
-			return errors.New("type mismatch")
+		if !isBinaryStringKind(v.Type()) {
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var data []byte
-		data, err = readBinaryString(reader)
-		if err != nil && err != io.EOF {
-			return err
+		data, err = d.readBinaryString()
+		if err != nil {
+			break
+		}
+
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(string(data))
+		case reflect.Array:
+			if len(data) != v.Len() {
+				return d.error(ErrLengthMismatch, v.Kind(), valueType)
+			}
+			reflect.Copy(v, reflect.ValueOf(data))
+		default:
+			v.SetBytes(data)
 		}
-		v.Set(reflect.ValueOf(data))
 	case TypeBool:
 		if v.Kind() != reflect.Bool {
-			return errors.New("type mismatch")
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
 		var val bool
-		val, err := readBool(reader)
-		if err != nil && err != io.EOF {
-			return err
-		}
+		val, err = d.readBool()
 		v.SetBool(val)
 	case TypeObject:
-		if v.Kind() != reflect.Struct {
-			return errors.New("type mismatch")
+		if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+			return d.error(ErrUnexpectedType, v.Kind(), valueType)
 		}
-		err = decodeObject(reader, v)
-		if err != nil && err != io.EOF {
-			return err
+		return d.decodeObject(v)
+	case TypeArray:
+		// an element of an array of arrays carries its own array type
+		var t byte
+		t, err = d.readType()
+		if err != nil {
+			break
 		}
+
+		if t&FlagArray == 0 || v.Kind() != reflect.Slice {
+			return d.error(ErrUnexpectedType, v.Kind(), t)
+		}
+		return d.decodeArray(t, v, wireType)
 	default:
-		log.Fatal("not implemented yet")
+		return d.error(ErrUnexpectedType, v.Kind(), valueType)
+	}
+
+	if err != nil {
+		return d.error(err, v.Kind(), valueType)
 	}
 
-	return err
+	return nil
 }
 
-func structFields(v reflect.Value) map[string]reflect.Value {
-	if v.Kind() != reflect.Struct {
-		log.Fatal("v is expected to be a struct")
+// skipValue reads and discards a value of the given wire type. Unlike decodeValue it doesn't need
+// a target so it's able to skip any value including nested sections and arrays of arrays
+func (d *decoder) skipValue(valueType byte) error {
+	if valueType&FlagArray != 0 {
+		return d.skipArray(valueType)
 	}
 
-	fields := make(map[string]reflect.Value)
-	for i := 0; i < v.NumField(); i++ {
-		name := v.Type().Field(i).Tag.Get("monerobinkv")
-		if len(name) == 0 {
-			continue
+	var err error
+	switch valueType {
+	case TypeInt64, TypeUint64, TypeDouble:
+		err = d.skip(8)
+	case TypeInt32, TypeUint32:
+		err = d.skip(4)
+	case TypeInt16, TypeUint16:
+		err = d.skip(2)
+	case TypeInt8, TypeUint8, TypeBool:
+		err = d.skip(1)
+	case TypeBinaryString:
+		var size uint64
+		size, err = d.unpackVarint()
+		if err == nil && d.capturing {
+			err = d.reserveCapture(size, true)
+		}
+		if err == nil {
+			err = d.skip(size)
+		}
+	case TypeObject:
+		return d.skipObject()
+	case TypeArray:
+		// an array of arrays element carries its own array type
+		var t byte
+		t, err = d.readType()
+		if err == nil && t&FlagArray == 0 {
+			return d.error(ErrUnexpectedType, reflect.Invalid, t)
 		}
 
-		fields[name] = v.Field(i)
+		if err == nil {
+			return d.skipArray(t)
+		}
+	default:
+		return d.error(ErrUnexpectedType, reflect.Invalid, valueType)
+	}
+
+	if err != nil {
+		return d.error(err, reflect.Invalid, valueType)
 	}
 
-	return fields
+	return nil
 }
 
-func decodeArray(reader io.Reader, arrayType byte, value reflect.Value) error {
-	size, err := unpackVarint(reader)
-	if err == io.EOF {
-		return ErrUnexpectedEof
+func (d *decoder) skipObject() error {
+	err := d.enter()
+	if err != nil {
+		return d.error(err, reflect.Invalid, TypeObject)
+	}
+	defer d.leave()
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkSectionEntries(size)
+	}
+
+	if err != nil {
+		return d.error(err, reflect.Invalid, TypeObject)
+	}
+
+	for i := uint64(0); i < size; i++ {
+		name, err := d.readName()
+		if err != nil {
+			return d.error(err, reflect.Invalid, TypeObject)
+		}
+
+		d.path.pushName(string(name))
+		t, err := d.readType()
+		if err != nil {
+			return d.error(err, reflect.Invalid, 0)
+		}
+
+		err = d.skipValue(t)
+		if err != nil {
+			return err
+		}
+		d.path.pop()
 	}
 
+	return nil
+}
+
+func (d *decoder) skipArray(arrayType byte) error {
+	err := d.enter()
 	if err != nil {
-		return err
+		return d.error(err, reflect.Invalid, arrayType)
 	}
+	defer d.leave()
 
-	newv := makeSlice(value, int(size))
-	value.Set(newv)
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkArray(size, 0)
+	}
+
+	if err != nil {
+		return d.error(err, reflect.Invalid, arrayType)
+	}
 
 	elemType := arrayType & ^FlagArray
 
-	for i := 0; i < int(size); i++ {
-		elem := value.Index(i)
-		err = decodeValue(reader, elemType, elem)
-		if err == io.EOF && i < int(size) - 1 {
-			return ErrUnexpectedEof
+	width := 0
+	switch elemType {
+	case TypeInt64, TypeUint64, TypeDouble:
+		width = 8
+	case TypeInt32, TypeUint32:
+		width = 4
+	case TypeInt16, TypeUint16:
+		width = 2
+	case TypeInt8, TypeUint8, TypeBool:
+		width = 1
+	}
+
+	if width != 0 {
+		if d.capturing {
+			err = d.reserveCapture(size*uint64(width), false)
+		}
+		if err == nil {
+			err = d.skip(size * uint64(width))
 		}
+		if err != nil {
+			return d.error(err, reflect.Invalid, arrayType)
+		}
+
+		return nil
+	}
 
+	for i := 0; i < int(size); i++ {
+		d.path.pushIndex(i)
+		err = d.skipValue(elemType)
 		if err != nil {
 			return err
 		}
+		d.path.pop()
 	}
 
 	return nil
 }
 
-func makeSlice(value reflect.Value, size int) reflect.Value {
-	if value.Kind() != reflect.Slice {
-		log.Fatal("value expected to be a slice")
+// isBinaryStringKind reports whether values of type t are decoded from binary strings
+func isBinaryStringKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// byteArrayBytes returns contents of a byte array, it's copied only if the array isn't addressable
+func byteArrayBytes(v reflect.Value) []byte {
+	if v.CanAddr() {
+		return v.Slice(0, v.Len()).Bytes()
+	}
+
+	data := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(data), v)
+	return data
+}
+
+func isNilPointer(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// indirect allocates nil pointers down the chain and returns the value pointed to. This way
+// a pointer field gets allocated only when its key is present in the message
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func (d *decoder) decodeArray(arrayType byte, value reflect.Value, wireType byte) error {
+	err := d.enter()
+	if err != nil {
+		return d.error(err, reflect.Slice, arrayType)
 	}
+	defer d.leave()
 
-	capacity := size + size/2
-	if capacity < 4 {
-		capacity = 4
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkArray(size, uint64(value.Type().Elem().Size()))
 	}
 
-	return reflect.MakeSlice(value.Type(), size, capacity)
+	if err != nil {
+		return d.error(err, reflect.Slice, arrayType)
+	}
+
+	// exactly size elements are allocated as checkArray has charged
+	value.Set(reflect.MakeSlice(value.Type(), int(size), int(size)))
+
+	elemType := arrayType & ^FlagArray
+
+	for i := 0; i < int(size); i++ {
+		d.path.pushIndex(i)
+		err = d.decodeValue(elemType, value.Index(i), wireType)
+		if err != nil {
+			return err
+		}
+		d.path.pop()
+	}
+
+	return nil
 }
@@ -2,6 +2,8 @@ package moneroproto
 
 import (
 	"bytes"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -152,4 +154,475 @@ func TestBinaryStringArrayDecode(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, expected, obj)
-}
\ No newline at end of file
+}
+
+type MapObject struct {
+	Peers map[string]uint32 `monerobinkv:"peers"`
+}
+
+func TestMapObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73,
+		0x0c, 0x08, 0x01, 0x61, 0x06, 0x01, 0x00, 0x00, 0x00, 0x01, 0x62, 0x06, 0x02, 0x00, 0x00, 0x00}
+
+	obj := MapObject{map[string]uint32{"b": 2, "a": 1}}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestMapObjectDecode(t *testing.T) {
+	expected := MapObject{map[string]uint32{"a": 1, "b": 2}}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x05, 0x70, 0x65, 0x65,
+		0x72, 0x73, 0x0c, 0x08, 0x01, 0x61, 0x06, 0x01, 0x00, 0x00, 0x00, 0x01, 0x62, 0x06, 0x02, 0x00, 0x00, 0x00})
+
+	var obj MapObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+func TestMapKeyTooLong(t *testing.T) {
+	key := strings.Repeat("k", 256)
+	obj := MapObject{map[string]uint32{"a": 1, key: 2}}
+
+	_, err := Marshal(obj)
+	assert.True(t, errors.Is(err, ErrNameTooLong))
+	assert.Equal(t, "peers."+key, err.(*SerializationError).Path)
+
+	delete(obj.Peers, key)
+	obj.Peers[key[1:]] = 2
+	_, err = Marshal(obj)
+	assert.Nil(t, err)
+}
+
+func TestTopLevelMapSerialize(t *testing.T) {
+	obj := map[string]SimpleObject{
+		"block1": {0x1122334455667788},
+		"block2": {0xaabbccddeeff00ff},
+	}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var nested NestedObjects
+	err = Read(bytes.NewReader(buffer.Bytes()), &nested)
+	assert.Nil(t, err)
+	assert.Equal(t, NestedObjects{obj["block1"], obj["block2"]}, nested)
+
+	var restored map[string]SimpleObject
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, obj, restored)
+}
+
+type StringObject struct {
+	Status string   `monerobinkv:"status"`
+	Peers  []string `monerobinkv:"peers"`
+}
+
+func TestStringObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
+		0x73, 0x0a, 0x08, 0x4f, 0x4b, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x8a, 0x08, 0x04, 0x61, 0x08, 0x62, 0x63}
+
+	obj := StringObject{"OK", []string{"a", "bc"}}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestStringObjectDecode(t *testing.T) {
+	expected := StringObject{"OK", []string{"a", "bc"}}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x06, 0x73, 0x74, 0x61,
+		0x74, 0x75, 0x73, 0x0a, 0x08, 0x4f, 0x4b, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x8a, 0x08, 0x04, 0x61, 0x08, 0x62,
+		0x63})
+
+	var obj StringObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+type ExtendedObject struct {
+	Extra   StringObject      `monerobinkv:"extra"`
+	Txs     uint64            `monerobinkv:"txs"`
+	Flag    bool              `monerobinkv:"flag"`
+	Peers   map[string]uint32 `monerobinkv:"peers"`
+	Objects []SimpleObject    `monerobinkv:"objects"`
+	Array   []uint16          `monerobinkv:"array"`
+	Ratio   float64           `monerobinkv:"ratio"`
+}
+
+func TestUnknownFieldsSkipped(t *testing.T) {
+	obj := ExtendedObject{
+		Extra:   StringObject{"OK", []string{"a", "bc"}},
+		Txs:     0x1122334455667788,
+		Flag:    true,
+		Peers:   map[string]uint32{"a": 1},
+		Objects: []SimpleObject{{1}, {2}},
+		Array:   []uint16{1, 2, 3},
+		Ratio:   0.5,
+	}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var restored SimpleObject
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{0x1122334455667788}, restored)
+}
+
+func TestUnknownArrayOfArraysSkipped(t *testing.T) {
+	// {"aa": [[1]], "txs": 2}
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x02, 0x61, 0x61, 0x8d,
+		0x04, 0x85, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x74, 0x78, 0x73, 0x05, 0x02, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00})
+
+	var obj SimpleObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{2}, obj)
+}
+
+func TestUnknownFieldStrict(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, ExtendedObject{Txs: 1})
+	assert.Nil(t, err)
+
+	var obj SimpleObject
+	err = ReadStrict(bytes.NewReader(buffer.Bytes()), &obj)
+
+	assert.True(t, errors.Is(err, ErrUnknownField))
+}
+
+type TaggedObject struct {
+	Height  int    `monerobinkv:"height,int64"`
+	Skipped uint64 `monerobinkv:"-"`
+	Helper  string
+	Empty   []uint32 `monerobinkv:"empty,omitempty"`
+	Small   []int    `monerobinkv:"small,uint8"`
+	Named   bool     `monerobinkv:",omitempty"`
+}
+
+func TestTaggedObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x0c, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68,
+		0x74, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x05, 0x73, 0x6d, 0x61, 0x6c, 0x6c, 0x88, 0x08, 0x01,
+		0xff, 0x05, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x0b, 0x01}
+
+	obj := TaggedObject{Height: -1, Skipped: 1, Helper: "helper", Small: []int{1, 255}, Named: true}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestTaggedObjectDecode(t *testing.T) {
+	expected := TaggedObject{Height: -1, Small: []int{1, 255}, Named: true}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x0c, 0x06, 0x68, 0x65, 0x69,
+		0x67, 0x68, 0x74, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x05, 0x73, 0x6d, 0x61, 0x6c, 0x6c, 0x88,
+		0x08, 0x01, 0xff, 0x05, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x0b, 0x01})
+
+	var obj TaggedObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+func TestWireTypeOverflow(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, TaggedObject{Small: []int{256}})
+
+	assert.True(t, errors.Is(err, ErrValueOverflow))
+	assert.Equal(t, "small[0]", err.(*SerializationError).Path)
+}
+
+func TestIntOverflow(t *testing.T) {
+	_, err := Marshal(struct {
+		Value int `monerobinkv:"value"`
+	}{1 << 40})
+	assert.True(t, errors.Is(err, ErrValueOverflow))
+	assert.Equal(t, "value", err.(*SerializationError).Path)
+
+	_, err = Marshal(struct {
+		Values []uint `monerobinkv:"values"`
+	}{[]uint{1, 1 << 32}})
+	assert.True(t, errors.Is(err, ErrValueOverflow))
+	assert.Equal(t, "values[1]", err.(*SerializationError).Path)
+
+	type IntObject struct {
+		Value int `monerobinkv:"value"`
+	}
+	data, err := Marshal(IntObject{-1 << 31})
+	assert.Nil(t, err)
+
+	var obj IntObject
+	err = Unmarshal(data, &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, -1<<31, obj.Value)
+}
+
+type InvalidTagObject struct {
+	Value string `monerobinkv:"value,uint32"`
+}
+
+func TestInvalidTag(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, InvalidTagObject{})
+
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+}
+
+type OptionalObject struct {
+	Credits *uint64         `monerobinkv:"credits"`
+	Block   *SimpleObject   `monerobinkv:"block"`
+	Blocks  []*SimpleObject `monerobinkv:"blocks"`
+}
+
+func TestOptionalObjectNilEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+		0x73, 0x8c, 0x00}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, OptionalObject{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestOptionalObjectSerialize(t *testing.T) {
+	credits := uint64(0)
+	obj := OptionalObject{
+		Credits: &credits,
+		Block:   &SimpleObject{1},
+		Blocks:  []*SimpleObject{{2}, {3}},
+	}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var restored OptionalObject
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, obj, restored)
+}
+
+func TestOptionalObjectMissingDecode(t *testing.T) {
+	var restored OptionalObject
+	err := Read(bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x00}), &restored)
+
+	assert.Nil(t, err)
+	assert.Nil(t, restored.Credits)
+	assert.Nil(t, restored.Block)
+}
+
+func TestNilArrayElementEncode(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, OptionalObject{Blocks: []*SimpleObject{{1}, nil}})
+
+	assert.True(t, errors.Is(err, ErrNilElement))
+	assert.Equal(t, "blocks[1]", err.(*SerializationError).Path)
+}
+
+type ByteArrayObject struct {
+	Key  [4]byte   `monerobinkv:"key"`
+	Keys [][2]byte `monerobinkv:"keys"`
+}
+
+func TestByteArrayObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x03, 0x6b, 0x65, 0x79, 0x0a, 0x10,
+		0xde, 0xad, 0xbe, 0xef, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x8a, 0x04, 0x08, 0xba, 0xbe}
+
+	obj := ByteArrayObject{[4]byte{0xde, 0xad, 0xbe, 0xef}, [][2]byte{{0xba, 0xbe}}}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestByteArrayObjectDecode(t *testing.T) {
+	expected := ByteArrayObject{[4]byte{0xde, 0xad, 0xbe, 0xef}, [][2]byte{{0xba, 0xbe}}}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x03, 0x6b, 0x65, 0x79,
+		0x0a, 0x10, 0xde, 0xad, 0xbe, 0xef, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x8a, 0x04, 0x08, 0xba, 0xbe})
+
+	var obj ByteArrayObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+func TestByteArrayLengthMismatch(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x03, 0x6b, 0x65, 0x79,
+		0x0a, 0x0c, 0xde, 0xad, 0xbe})
+
+	var obj ByteArrayObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrLengthMismatch))
+	assert.Equal(t, "key", err.(*SerializationError).Path)
+
+	reader = bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x03, 0x6b, 0x65, 0x79,
+		0x0a, 0x00})
+
+	obj.Key = [4]byte{0xde, 0xad, 0xbe, 0xef}
+	err = Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrLengthMismatch))
+	assert.Equal(t, "key", err.(*SerializationError).Path)
+}
+
+type PodBlobObject struct {
+	Values []uint32  `monerobinkv:"values,podblob"`
+	Keys   [][2]byte `monerobinkv:"keys,podblob"`
+}
+
+func TestPodBlobObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65,
+		0x73, 0x0a, 0x20, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x0a, 0x10, 0xba,
+		0xbe, 0xca, 0xfe}
+
+	obj := PodBlobObject{[]uint32{1, 2}, [][2]byte{{0xba, 0xbe}, {0xca, 0xfe}}}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestPodBlobObjectDecode(t *testing.T) {
+	expected := PodBlobObject{[]uint32{1, 2}, [][2]byte{{0xba, 0xbe}, {0xca, 0xfe}}}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x08, 0x06, 0x76, 0x61, 0x6c,
+		0x75, 0x65, 0x73, 0x0a, 0x20, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x0a,
+		0x10, 0xba, 0xbe, 0xca, 0xfe})
+
+	var obj PodBlobObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+func TestPodBlobLengthMismatch(t *testing.T) {
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x06, 0x76, 0x61, 0x6c,
+		0x75, 0x65, 0x73, 0x0a, 0x0c, 0x01, 0x00, 0x00})
+
+	var obj PodBlobObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrLengthMismatch))
+	assert.Equal(t, "values", err.(*SerializationError).Path)
+}
+
+type InvalidPodBlobObject struct {
+	Values []string `monerobinkv:"values,podblob"`
+}
+
+func TestInvalidPodBlobTag(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, InvalidPodBlobObject{})
+
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+}
+
+type NestedArrayObject struct {
+	Matrix [][]uint16 `monerobinkv:"m"`
+}
+
+func TestNestedArrayObjectEncode(t *testing.T) {
+	expected := []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x01, 0x6d, 0x8d, 0x08, 0x87, 0x08,
+		0x01, 0x00, 0x02, 0x00, 0x87, 0x00}
+
+	obj := NestedArrayObject{[][]uint16{{1, 2}, {}}}
+	buffer := bytes.Buffer{}
+
+	err := Write(&buffer, obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buffer.Bytes())
+}
+
+func TestNestedArrayObjectDecode(t *testing.T) {
+	expected := NestedArrayObject{[][]uint16{{1, 2}, {}}}
+
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x01, 0x6d, 0x8d, 0x08,
+		0x87, 0x08, 0x01, 0x00, 0x02, 0x00, 0x87, 0x00})
+
+	var obj NestedArrayObject
+	err := Read(reader, &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, obj)
+}
+
+type DeepArrayObject struct {
+	Objects [][]SimpleObject `monerobinkv:"objects"`
+	Cube    [][][]uint64     `monerobinkv:"cube"`
+	Blobs   [][][]byte       `monerobinkv:"blobs"`
+	Small   [][]uint32       `monerobinkv:"small,uint8"`
+}
+
+func TestDeepArrayObjectSerialize(t *testing.T) {
+	obj := DeepArrayObject{
+		Objects: [][]SimpleObject{{{1}, {2}}, {{3}}},
+		Cube:    [][][]uint64{{{1, 2}, {3}}, {{4}}},
+		Blobs:   [][][]byte{{{0xde, 0xad}, {}}, {{0xbe, 0xef}}},
+		Small:   [][]uint32{{0xff}, {1, 2}},
+	}
+
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, obj)
+	assert.Nil(t, err)
+
+	var restored DeepArrayObject
+	err = Read(bytes.NewReader(buffer.Bytes()), &restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, obj, restored)
+
+	// the same bytes are understood by the document model
+	section, err := ReadSection(bytes.NewReader(buffer.Bytes()))
+	assert.Nil(t, err)
+
+	cube, err := section.GetArray("cube")
+	assert.Nil(t, err)
+	assert.Len(t, cube, 2)
+	assert.Equal(t, TypeArray|FlagArray, cube[0].Type)
+}
+
+func TestNestedArrayTypeMismatch(t *testing.T) {
+	// {"m": [[1]]} where the inner array is of uint64
+	reader := bytes.NewReader([]byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01, 0x04, 0x01, 0x6d, 0x8d, 0x04,
+		0x85, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+	var obj NestedArrayObject
+	err := Read(reader, &obj)
+
+	assert.True(t, errors.Is(err, ErrUnexpectedType))
+	assert.Equal(t, "m[0][0]", err.(*SerializationError).Path)
+}
@@ -0,0 +1,172 @@
+package moneroproto
+
+import (
+	"reflect"
+)
+
+// ElementHandler receives the elements of a streamed array, see StreamArray. elem is a pointer to
+// a newly decoded element, the handler may keep it. Returning an error stops decoding
+type ElementHandler func(index int, elem interface{}) error
+
+// StreamArray makes Decoder pass the elements of the array field name of the message's root struct
+// to handler one at a time as they are decoded instead of storing them, the field is left nil.
+// Other fields are decoded as usual. Memory taken by an element isn't charged against
+// Limits.MaxAllocation once the handler returns
+func StreamArray(name string, handler ElementHandler) Option {
+	return func(o *options) {
+		if o.streams == nil {
+			o.streams = make(map[string]ElementHandler)
+		}
+
+		o.streams[name] = handler
+	}
+}
+
+// streamHandler returns the handler of field f if it's a streamed field of the root struct
+func (d *decoder) streamHandler(f *fieldCodec) (ElementHandler, bool) {
+	if d.depth != 1 || len(d.streams) == 0 {
+		return nil, false
+	}
+
+	handler, ok := d.streams[f.tag.name]
+	return handler, ok
+}
+
+// decodeStream reads a tagged array of elements of slice type t passing them to handler
+func (d *decoder) decodeStream(handler ElementHandler, f *fieldCodec, t reflect.Type) error {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 || f.tag.podBlob {
+		return d.error(ErrUnsupportedType, t.Kind(), 0)
+	}
+
+	arrayType, err := d.readType()
+	if err != nil {
+		return d.error(err, reflect.Slice, 0)
+	}
+
+	if arrayType&FlagArray == 0 {
+		return d.error(ErrUnexpectedType, reflect.Slice, arrayType)
+	}
+
+	err = d.enter()
+	if err != nil {
+		return d.error(err, reflect.Slice, arrayType)
+	}
+	defer d.leave()
+
+	size, err := d.unpackVarint()
+	if err == nil {
+		err = d.checkArray(size, 0)
+	}
+
+	if err != nil {
+		return d.error(err, reflect.Slice, arrayType)
+	}
+
+	elemType := arrayType & ^FlagArray
+	for i := 0; i < int(size); i++ {
+		d.path.pushIndex(i)
+		allocated := d.allocated
+		elem := reflect.New(t.Elem())
+		err = d.decodeValue(elemType, elem.Elem(), f.tag.wireType)
+		if err != nil {
+			return err
+		}
+		d.allocated = allocated
+
+		err = handler(i, elem.Interface())
+		if err != nil {
+			return d.error(err, t.Elem().Kind(), elemType)
+		}
+		d.path.pop()
+	}
+
+	return nil
+}
+
+// ElementProducer returns the element at index of a produced array, see ProduceArray. Returning
+// an error stops encoding
+type ElementProducer func(index int) (interface{}, error)
+
+// ArrayProducer supplies the elements of an array field of a single message, see ProduceArray
+type ArrayProducer struct {
+	name     string
+	count    int
+	producer ElementProducer
+}
+
+// ProduceArray makes Encoder.EncodeWith write count elements returned by producer one at a time as
+// the array field name of the message's root struct instead of the field's value, e.g. to send
+// blocks read from a database without building a slice of them. Elements must be values of the
+// field's element type or pointers to them. Encoder writes completed parts of the message out as
+// it goes, so the elements don't stay in memory
+func ProduceArray(name string, count int, producer ElementProducer) ArrayProducer {
+	return ArrayProducer{name: name, count: count, producer: producer}
+}
+
+// producerOf returns the producer of field f if it's a produced field of the root struct
+func (e *encoder) producerOf(f *fieldCodec) (ArrayProducer, bool) {
+	if len(e.path) != 0 {
+		return ArrayProducer{}, false
+	}
+
+	for _, p := range e.producers {
+		if p.name == f.tag.name {
+			return p, true
+		}
+	}
+
+	return ArrayProducer{}, false
+}
+
+// encodeProduced writes a tagged array of elements of slice type t taking them from p
+func (e *encoder) encodeProduced(p ArrayProducer, f *fieldCodec, t reflect.Type) error {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 || f.tag.podBlob ||
+		isMarshalerType(t.Elem()) {
+		return e.error(ErrUnsupportedType, t.Kind(), 0)
+	}
+
+	elemType, err := getWireObjectType(t.Elem())
+	if err != nil {
+		return e.error(err, t.Elem().Kind(), 0)
+	}
+
+	wireType := f.tag.wireType
+	if wireType != 0 && elemType != TypeArray {
+		elemType = wireType
+	}
+
+	e.writeType(elemType | FlagArray)
+	err = e.packVarint(uint64(p.count))
+	if err != nil {
+		return e.error(err, reflect.Slice, elemType|FlagArray)
+	}
+
+	for i := 0; i < p.count; i++ {
+		e.path.pushIndex(i)
+		elem, err := p.producer(i)
+		if err != nil {
+			return e.error(err, t.Elem().Kind(), elemType)
+		}
+
+		v := reflect.ValueOf(elem)
+		if v.Kind() == reflect.Ptr && v.Type().Elem() == t.Elem() {
+			v = v.Elem()
+		}
+
+		if !v.IsValid() || v.Type() != t.Elem() {
+			return e.error(ErrUnexpectedType, v.Kind(), elemType)
+		}
+
+		err = e.encodeArrayElement(v, wireType)
+		if err == nil {
+			err = e.maybeFlush()
+		}
+
+		if err != nil {
+			return e.error(err, t.Elem().Kind(), elemType)
+		}
+		e.path.pop()
+	}
+
+	return nil
+}
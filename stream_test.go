@@ -0,0 +1,139 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamArray(t *testing.T) {
+	expected := makeLargeGetBlocksFastResponse()
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, expected)
+	assert.Nil(t, err)
+
+	var blocks []BlockCompleteEntry
+	handler := func(index int, elem interface{}) error {
+		assert.Equal(t, len(blocks), index)
+		blocks = append(blocks, *elem.(*BlockCompleteEntry))
+		return nil
+	}
+
+	// the whole response doesn't fit the budget but a single block does
+	limits := DefaultLimits
+	limits.MaxAllocation = 1 << 20
+	dec := NewDecoder(&buffer, StreamArray("blocks", handler), WithLimits(limits))
+
+	var obj GetBlocksFastResponse
+	err = dec.Decode(&obj)
+	assert.Nil(t, err)
+	assert.Nil(t, obj.Blocks)
+	assert.Equal(t, expected.Blocks, blocks)
+	assert.Equal(t, expected.OutputIndices, obj.OutputIndices)
+	assert.Equal(t, expected.Status, obj.Status)
+}
+
+func TestStreamArrayHandlerError(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	stop := errors.New("stop")
+	handler := func(index int, elem interface{}) error {
+		return stop
+	}
+
+	var obj GetBlocksFastResponse
+	err = NewDecoder(bytes.NewReader(data), StreamArray("blocks", handler)).Decode(&obj)
+	assert.True(t, errors.Is(err, stop))
+	assert.Equal(t, "blocks[0]", err.(*SerializationError).Path)
+}
+
+func TestStreamArrayRootOnly(t *testing.T) {
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	calls := 0
+	handler := func(index int, elem interface{}) error {
+		calls++
+		return nil
+	}
+
+	// "txs" arrays of nested blocks aren't streamed
+	var obj GetBlocksFastResponse
+	err = NewDecoder(bytes.NewReader(data), StreamArray("txs", handler)).Decode(&obj)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedGetBlocksFastResponse, obj)
+	assert.Equal(t, 0, calls)
+}
+
+func TestProduceArray(t *testing.T) {
+	expected := makeLargeGetBlocksFastResponse()
+	producer := func(index int) (interface{}, error) {
+		return &expected.Blocks[index], nil
+	}
+
+	obj := expected
+	obj.Blocks = nil
+	writer := &countingWriter{}
+	enc := NewEncoder(writer)
+	err := enc.EncodeWith(&obj, ProduceArray("blocks", len(expected.Blocks), producer))
+	assert.Nil(t, err)
+
+	data, err := Marshal(&expected)
+	assert.Nil(t, err)
+	assert.Equal(t, data, writer.buffer.Bytes())
+	assert.True(t, writer.writes > 1)
+
+	// the producer applied to the previous message only
+	writer.buffer.Reset()
+	err = enc.Encode(&obj)
+	assert.Nil(t, err)
+
+	empty, err := Marshal(&obj)
+	assert.Nil(t, err)
+	assert.Equal(t, empty, writer.buffer.Bytes())
+
+	writer.buffer.Reset()
+	err = enc.EncodeWith(&obj, ProduceArray("blocks", len(expected.Blocks), producer))
+	assert.Nil(t, err)
+	assert.Equal(t, data, writer.buffer.Bytes())
+}
+
+func TestProduceArrayEmpty(t *testing.T) {
+	producer := func(index int) (interface{}, error) {
+		return nil, errors.New("unexpected call")
+	}
+
+	buffer := bytes.Buffer{}
+	err := NewEncoder(&buffer).EncodeWith(&GetBlocksFastResponse{Status: "OK"}, ProduceArray("blocks", 0, producer))
+	assert.Nil(t, err)
+
+	var obj GetBlocksFastResponse
+	err = Read(&buffer, &obj)
+	assert.Nil(t, err)
+	assert.Equal(t, []BlockCompleteEntry{}, obj.Blocks)
+	assert.Equal(t, "OK", obj.Status)
+}
+
+func TestProduceArrayErrors(t *testing.T) {
+	stop := errors.New("stop")
+	producers := []ElementProducer{
+		func(index int) (interface{}, error) {
+			return nil, stop
+		},
+		func(index int) (interface{}, error) {
+			return "block", nil
+		},
+	}
+	expected := []error{stop, ErrUnexpectedType}
+
+	for i, producer := range producers {
+		enc := NewEncoder(&bytes.Buffer{})
+		err := enc.EncodeWith(&GetBlocksFastResponse{}, ProduceArray("blocks", 1, producer))
+
+		assert.True(t, errors.Is(err, expected[i]), "%v", err)
+		assert.Equal(t, "blocks[0]", err.(*SerializationError).Path)
+	}
+}
@@ -0,0 +1,280 @@
+package moneroproto
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+const tagName = "monerobinkv"
+
+var (
+	ErrInvalidTag    = errors.New("invalid struct tag")
+	ErrValueOverflow = errors.New("value doesn't fit the wire type")
+)
+
+// wireTypeNames are the names of wire types allowed as a tag option
+var wireTypeNames = map[string]byte{
+	"int64":  TypeInt64,
+	"int32":  TypeInt32,
+	"int16":  TypeInt16,
+	"int8":   TypeInt8,
+	"uint64": TypeUint64,
+	"uint32": TypeUint32,
+	"uint16": TypeUint16,
+	"uint8":  TypeUint8,
+}
+
+// fieldTag is a parsed monerobinkv tag. The tag format is `monerobinkv:"name[,option]..."`
+// where options are:
+//
+//	omitempty - don't write the field if it has zero value (false, 0, empty string, container or nil pointer)
+//	int64, int32, ..., uint8 - write an integer field (or integer slice elements) with this wire type
+//	                           instead of the one derived from the Go type
+//	podblob - pack a slice of fixed-size values into a single binary string like epee's
+//	          KV_SERIALIZE_CONTAINER_POD_AS_BLOB does, e.g. []moneroutil.Hash
+//	unknown - collect the entries the struct doesn't declare and write them back, the field must be
+//	          []RawField and it takes no name or other options
+//
+// A field tagged with "-" is excluded. A field with an empty name uses its Go name
+type fieldTag struct {
+	name      string
+	omitEmpty bool
+	wireType  byte
+	podBlob   bool
+	unknown   bool
+}
+
+// parseTag parses the tag of a struct field. ok is false if the field must not be serialized:
+// it has no tag, it's excluded with "-" or it's unexported
+func parseTag(field reflect.StructField) (tag fieldTag, ok bool, err error) {
+	str, ok := field.Tag.Lookup(tagName)
+	if !ok || str == "-" || field.PkgPath != "" {
+		return fieldTag{}, false, nil
+	}
+
+	parts := strings.Split(str, ",")
+	if str == ",unknown" {
+		if field.Type != rawFieldsType {
+			return fieldTag{}, false, ErrInvalidTag
+		}
+
+		return fieldTag{unknown: true}, true, nil
+	}
+
+	tag.name = parts[0]
+	if len(tag.name) == 0 {
+		tag.name = field.Name
+	}
+
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			tag.omitEmpty = true
+			continue
+		}
+
+		if option == "podblob" {
+			tag.podBlob = true
+			continue
+		}
+
+		wireType, known := wireTypeNames[option]
+		if !known || tag.wireType != 0 {
+			return fieldTag{}, false, ErrInvalidTag
+		}
+
+		tag.wireType = wireType
+	}
+
+	if tag.wireType != 0 && !isIntegerKind(integerBaseType(field.Type).Kind()) {
+		return fieldTag{}, false, ErrInvalidTag
+	}
+
+	if tag.podBlob && (tag.wireType != 0 || !isPodSlice(field.Type)) {
+		return fieldTag{}, false, ErrInvalidTag
+	}
+
+	return tag, true, nil
+}
+
+// integerBaseType strips pointers and slices a wire type override may be applied through
+func integerBaseType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return true
+	}
+
+	return false
+}
+
+func isSignedKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return true
+	}
+
+	return false
+}
+
+// isEmptyValue reports whether v is a zero value for omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// integerWireRange returns bounds of an integer wire type
+func integerWireRange(wireType byte) (min int64, max uint64) {
+	switch wireType {
+	case TypeInt64:
+		return -1 << 63, 1<<63 - 1
+	case TypeInt32:
+		return -1 << 31, 1<<31 - 1
+	case TypeInt16:
+		return -1 << 15, 1<<15 - 1
+	case TypeInt8:
+		return -1 << 7, 1<<7 - 1
+	case TypeUint64:
+		return 0, 1<<64 - 1
+	case TypeUint32:
+		return 0, 1<<32 - 1
+	case TypeUint16:
+		return 0, 1<<16 - 1
+	default:
+		return 0, 1<<8 - 1
+	}
+}
+
+// integerBits returns bit representation of an integer Go value v if it fits the wire type
+func integerBits(v reflect.Value, wireType byte) (uint64, error) {
+	min, max := integerWireRange(wireType)
+	if isSignedKind(v.Kind()) {
+		val := v.Int()
+		if val < min || (val > 0 && uint64(val) > max) {
+			return 0, ErrValueOverflow
+		}
+
+		return uint64(val), nil
+	}
+
+	val := v.Uint()
+	if val > max {
+		return 0, ErrValueOverflow
+	}
+
+	return val, nil
+}
+
+// writeIntegerBlob writes an integer value without type tag as the wire type, v must fit the type
+func writeIntegerBlob(writer *encoder, v reflect.Value, wireType byte) error {
+	bits, err := integerBits(v, wireType)
+	if err != nil {
+		return err
+	}
+
+	switch wireType {
+	case TypeInt64, TypeUint64:
+		writer.writeUint64Blob(bits)
+	case TypeInt32, TypeUint32:
+		writer.writeUint32Blob(uint32(bits))
+	case TypeInt16, TypeUint16:
+		writer.writeUint16Blob(uint16(bits))
+	default:
+		writer.writeUint8Blob(uint8(bits))
+	}
+
+	return nil
+}
+
+// readInteger reads an integer of the wire type and stores it into an integer Go value v
+func readInteger(reader *decoder, v reflect.Value, wireType byte) error {
+	var signed int64
+	var unsigned uint64
+	var err error
+	switch wireType {
+	case TypeInt64:
+		signed, err = reader.readInt64()
+	case TypeInt32:
+		var val int32
+		val, err = reader.readInt32()
+		signed = int64(val)
+	case TypeInt16:
+		var val int16
+		val, err = reader.readInt16()
+		signed = int64(val)
+	case TypeInt8:
+		var val int8
+		val, err = reader.readInt8()
+		signed = int64(val)
+	case TypeUint64:
+		unsigned, err = reader.readUint64()
+	case TypeUint32:
+		var val uint32
+		val, err = reader.readUint32()
+		unsigned = uint64(val)
+	case TypeUint16:
+		var val uint16
+		val, err = reader.readUint16()
+		unsigned = uint64(val)
+	default:
+		var val uint8
+		val, err = reader.readUint8()
+		unsigned = uint64(val)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	isSigned := wireType == TypeInt64 || wireType == TypeInt32 || wireType == TypeInt16 || wireType == TypeInt8
+	if isSigned && signed >= 0 {
+		isSigned = false
+		unsigned = uint64(signed)
+	}
+
+	if isSignedKind(v.Kind()) {
+		if !isSigned && (unsigned > 1<<63-1 || v.OverflowInt(int64(unsigned))) {
+			return ErrValueOverflow
+		}
+
+		if isSigned && v.OverflowInt(signed) {
+			return ErrValueOverflow
+		}
+
+		if !isSigned {
+			signed = int64(unsigned)
+		}
+
+		v.SetInt(signed)
+		return nil
+	}
+
+	if isSigned || v.OverflowUint(unsigned) {
+		return ErrValueOverflow
+	}
+
+	v.SetUint(unsigned)
+	return nil
+}
@@ -0,0 +1,159 @@
+package moneroproto
+
+import (
+	"reflect"
+	"sync"
+)
+
+// structCodec holds serialization metadata of a struct type. It's built once per type on first use
+// and shared by all encoders and decoders, like encoding/json does
+type structCodec struct {
+	fields []fieldCodec
+	byName map[string]int
+	// unknown is the index of the struct field collecting unknown entries or -1, see RawField
+	unknown int
+	// err is the error found in the type's tags, the type can't be serialized if it's set
+	err error
+}
+
+// fieldCodec describes a single serializable field
+type fieldCodec struct {
+	index  int
+	tag    fieldTag
+	encode encodeFunc
+	decode decodeFunc
+}
+
+// encodeFunc writes a field value along with its type tag
+type encodeFunc func(e *encoder, v reflect.Value, level int) error
+
+// decodeFunc reads a tagged value into a field
+type decodeFunc func(d *decoder, v reflect.Value) error
+
+var structCodecs sync.Map // map[reflect.Type]*structCodec
+
+// cachedStructCodec returns the codec of struct type t
+func cachedStructCodec(t reflect.Type) (*structCodec, error) {
+	if c, ok := structCodecs.Load(t); ok {
+		return c.(*structCodec), c.(*structCodec).err
+	}
+
+	c, _ := structCodecs.LoadOrStore(t, newStructCodec(t))
+	return c.(*structCodec), c.(*structCodec).err
+}
+
+func newStructCodec(t reflect.Type) *structCodec {
+	c := &structCodec{byName: make(map[string]int, t.NumField()), unknown: -1}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok, err := parseTag(field)
+		if err != nil {
+			return &structCodec{err: err}
+		}
+
+		if !ok {
+			continue
+		}
+
+		if tag.unknown {
+			if c.unknown >= 0 {
+				return &structCodec{err: ErrInvalidTag}
+			}
+
+			c.unknown = i
+			continue
+		}
+
+		c.byName[tag.name] = len(c.fields)
+		c.fields = append(c.fields, fieldCodec{
+			index:  i,
+			tag:    tag,
+			encode: newFieldEncoder(field.Type, tag),
+			decode: newFieldDecoder(field.Type, tag),
+		})
+	}
+
+	return c
+}
+
+// field looks up a field by its wire name
+func (c *structCodec) field(name []byte) (*fieldCodec, bool) {
+	i, ok := c.byName[string(name)]
+	if !ok {
+		return nil, false
+	}
+
+	return &c.fields[i], true
+}
+
+// skip reports whether the field with value v isn't written
+func (f *fieldCodec) skip(v reflect.Value) bool {
+	if f.tag.omitEmpty && isEmptyValue(v) {
+		return true
+	}
+
+	// nil pointers and raw entries are optional fields which aren't set
+	return isNilPointer(v) || (v.Type() == rawEntryType && v.Len() == 0)
+}
+
+// isPlainType reports whether values of type t are encoded by reflection only, without
+// a Marshaler, Unmarshaler or pointer in the way
+func isPlainType(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && t != rawEntryType &&
+		!isMarshalerType(t) && !reflect.PtrTo(t).Implements(unmarshalerType)
+}
+
+func newFieldEncoder(t reflect.Type, tag fieldTag) encodeFunc {
+	if tag.podBlob {
+		return func(e *encoder, v reflect.Value, level int) error {
+			return e.encodePodBlob(v)
+		}
+	}
+
+	if isPlainType(t) && tag.wireType == 0 {
+		valueType, err := getWireObjectType(t)
+		if err == nil && valueType != TypeObject && valueType != TypeArray {
+			if t.Kind() == reflect.Slice {
+				// []byte
+				return func(e *encoder, v reflect.Value, level int) error {
+					e.writeType(TypeBinaryString)
+					return e.writeBinaryStringBlob(v.Bytes())
+				}
+			}
+
+			return func(e *encoder, v reflect.Value, level int) error {
+				e.writeType(valueType)
+				return e.encodeArrayElement(v, 0)
+			}
+		}
+	}
+
+	wireType := tag.wireType
+	return func(e *encoder, v reflect.Value, level int) error {
+		return e.doEncode(v, level, wireType)
+	}
+}
+
+func newFieldDecoder(t reflect.Type, tag fieldTag) decodeFunc {
+	if tag.podBlob {
+		return func(d *decoder, v reflect.Value) error {
+			return d.decodePodBlob(v)
+		}
+	}
+
+	wireType := tag.wireType
+	if isPlainType(t) && t.Kind() != reflect.Slice {
+		return func(d *decoder, v reflect.Value) error {
+			valueType, err := d.readType()
+			if err != nil {
+				return d.error(err, v.Kind(), 0)
+			}
+
+			return d.decodeBuiltin(valueType, v, wireType)
+		}
+	}
+
+	return func(d *decoder, v reflect.Value) error {
+		return d.doDecode(v, wireType)
+	}
+}
@@ -0,0 +1,78 @@
+package moneroproto
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructCodecCached(t *testing.T) {
+	first, err := cachedStructCodec(reflect.TypeOf(GetBlocksFastRequest{}))
+	assert.Nil(t, err)
+
+	second, err := cachedStructCodec(reflect.TypeOf(GetBlocksFastRequest{}))
+	assert.Nil(t, err)
+	assert.True(t, first == second)
+
+	names := make([]string, 0, len(first.fields))
+	for _, f := range first.fields {
+		names = append(names, f.tag.name)
+	}
+	assert.Equal(t, []string{"client", "block_ids", "start_height", "prune", "no_miner_tx"}, names)
+
+	f, ok := first.field([]byte("start_height"))
+	assert.True(t, ok)
+	assert.Equal(t, 2, f.index)
+
+	_, ok = first.field([]byte("unknown"))
+	assert.False(t, ok)
+}
+
+func TestStructCodecInvalidTagCached(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		_, err := Marshal(InvalidTagObject{})
+		assert.True(t, errors.Is(err, ErrInvalidTag))
+	}
+}
+
+func TestConcurrentSerialize(t *testing.T) {
+	wg := sync.WaitGroup{}
+	results := make([]GetBlocksFastResponse, 8)
+	errs := make([]error, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			data, err := Marshal(&expectedGetBlocksFastResponse)
+			if err == nil {
+				err = Unmarshal(data, &results[i])
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, expectedGetBlocksFastResponse, results[i])
+	}
+}
+
+func TestMarshalAllocations(t *testing.T) {
+	buf := make([]byte, 0, 1024)
+	obj := &GetBlocksFastRequest{Client: "wallet", StartHeight: 100, Prune: true}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err := AppendMarshal(buf, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// values are appended to the buffer and field metadata is cached, only encoder state is allocated
+	assert.True(t, allocs <= 5, "%v allocations", allocs)
+}
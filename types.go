@@ -48,7 +48,7 @@ func HashesToByteSlice(hashes []moneroutil.Hash) []byte {
 //TODO: make hashes []*moneroutil.Hash
 func ByteSliceToHashes(hashes []byte) (error, []moneroutil.Hash) {
 	if len(hashes)%moneroutil.HashLength != 0 {
-		panic("corrupted hashes slice")
+		return ErrLengthMismatch, nil
 	}
 
 	hashesCount := len(hashes) / moneroutil.HashLength
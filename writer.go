@@ -0,0 +1,122 @@
+package moneroproto
+
+import (
+	"math"
+)
+
+// flushSize is the size of encoded data an encoder with a writer accumulates before writing it out
+const flushSize = 64 * 1024
+
+// The methods below append primitive values to the encoder's buffer. Appending never fails,
+// write errors of the underlying writer are reported by flush
+
+// Write appends p to the buffer, it makes encoder an io.Writer for encoding/binary
+func (e *encoder) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	return len(p), nil
+}
+
+func (e *encoder) writeUint8Blob(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) writeType(t byte) {
+	e.buf = append(e.buf, t)
+}
+
+func (e *encoder) writeUint64Blob(val uint64) {
+	e.buf = append(e.buf, byte(val), byte(val>>8), byte(val>>16), byte(val>>24),
+		byte(val>>32), byte(val>>40), byte(val>>48), byte(val>>56))
+}
+
+func (e *encoder) writeUint32Blob(val uint32) {
+	e.buf = append(e.buf, byte(val), byte(val>>8), byte(val>>16), byte(val>>24))
+}
+
+func (e *encoder) writeUint16Blob(val uint16) {
+	e.buf = append(e.buf, byte(val), byte(val>>8))
+}
+
+func (e *encoder) writeFloat64Blob(val float64) {
+	e.writeUint64Blob(math.Float64bits(val))
+}
+
+func (e *encoder) writeBoolBlob(val bool) {
+	if val {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) packVarint(val uint64) error {
+	switch {
+	case val <= 63:
+		e.buf = append(e.buf, byte(val<<2)|MarkByte)
+	case val <= 16383:
+		e.writeUint16Blob(uint16(val<<2) | uint16(MarkWord))
+	case val <= 1073741823:
+		e.writeUint32Blob(uint32(val<<2) | uint32(MarkDWord))
+	case val <= 4611686018427387903:
+		e.writeUint64Blob(val<<2 | uint64(MarkInt64))
+	default:
+		return ErrVarintTooBig
+	}
+
+	return nil
+}
+
+// writeName writes a section entry name, its length prefix is a single byte
+func (e *encoder) writeName(name string) error {
+	if len(name) > math.MaxUint8 {
+		return ErrNameTooLong
+	}
+
+	e.buf = append(e.buf, byte(len(name)))
+	e.buf = append(e.buf, name...)
+	return nil
+}
+
+// writeBinaryStringBlob writes a binary string without the type tag
+func (e *encoder) writeBinaryStringBlob(val []byte) error {
+	err := e.packVarint(uint64(len(val)))
+	if err != nil {
+		return err
+	}
+
+	e.buf = append(e.buf, val...)
+	return nil
+}
+
+// writeStringBlob works like writeBinaryStringBlob but doesn't convert val into a byte slice
+func (e *encoder) writeStringBlob(val string) error {
+	err := e.packVarint(uint64(len(val)))
+	if err != nil {
+		return err
+	}
+
+	e.buf = append(e.buf, val...)
+	return nil
+}
+
+// flush writes the buffered data out if the encoder has a writer. The buffer is kept for reuse
+func (e *encoder) flush() error {
+	if e.writer == nil || len(e.buf) == 0 {
+		return nil
+	}
+
+	n, err := e.writer.Write(e.buf)
+	e.flushed += int64(n)
+	e.buf = e.buf[:0]
+	return err
+}
+
+// maybeFlush flushes the buffer once it grows over flushSize, so encoding a big message through
+// an Encoder doesn't hold all of it in memory
+func (e *encoder) maybeFlush() error {
+	if e.writer == nil || len(e.buf) < flushSize {
+		return nil
+	}
+
+	return e.flush()
+}
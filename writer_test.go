@@ -0,0 +1,123 @@
+package moneroproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingWriter counts Write calls
+type countingWriter struct {
+	buffer bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.buffer.Write(p)
+}
+
+func TestMarshalMatchesWrite(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := Write(&buffer, &expectedGetBlocksFastResponse)
+	assert.Nil(t, err)
+
+	data, err := Marshal(&expectedGetBlocksFastResponse)
+
+	assert.Nil(t, err)
+	assert.Equal(t, buffer.Bytes(), data)
+}
+
+func TestAppendMarshal(t *testing.T) {
+	prefix := []byte("levin")
+
+	data, err := AppendMarshal(prefix, SimpleObject{0x1122334455667788})
+	assert.Nil(t, err)
+
+	expected, err := Marshal(SimpleObject{0x1122334455667788})
+	assert.Nil(t, err)
+	assert.Equal(t, append([]byte("levin"), expected...), data)
+
+	var obj SimpleObject
+	err = Unmarshal(data[len(prefix):], &obj)
+
+	assert.Nil(t, err)
+	assert.Equal(t, SimpleObject{0x1122334455667788}, obj)
+}
+
+func TestAppendMarshalErrorOffset(t *testing.T) {
+	data, err := AppendMarshal([]byte("levin"), OptionalObject{Blocks: []*SimpleObject{nil}})
+
+	assert.Equal(t, []byte("levin"), data)
+	assert.True(t, errors.Is(err, ErrNilElement))
+	// preamble, entries count, name and array type with count
+	assert.Equal(t, int64(19), err.(*SerializationError).Offset)
+}
+
+func TestMarshalNotSectionRoot(t *testing.T) {
+	value := 5
+	roots := []interface{}{5, &value, []uint64{1}, "status", nil}
+
+	for _, root := range roots {
+		data, err := Marshal(root)
+		assert.Len(t, data, 0)
+		assert.True(t, errors.Is(err, ErrUnsupportedType), "%v", root)
+
+		buffer := bytes.Buffer{}
+		err = Write(&buffer, root)
+		assert.True(t, errors.Is(err, ErrUnsupportedType), "%v", root)
+		assert.Equal(t, 0, buffer.Len())
+	}
+}
+
+func TestEncoderFlushesLargeMessage(t *testing.T) {
+	resp := makeLargeGetBlocksFastResponse()
+
+	expected, err := Marshal(&resp)
+	assert.Nil(t, err)
+
+	writer := &countingWriter{}
+	err = NewEncoder(writer).Encode(&resp)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, writer.buffer.Bytes())
+	assert.True(t, writer.writes > 1)
+	assert.True(t, writer.writes <= len(expected)/flushSize+1)
+}
+
+func BenchmarkMarshalGetBlocksFastResponse(b *testing.B) {
+	resp := makeLargeGetBlocksFastResponse()
+	buf := make([]byte, 0, 8*1024*1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := AppendMarshal(buf[:0], &resp)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.SetBytes(int64(len(data)))
+	}
+}
+
+func BenchmarkWriteGetBlocksFastResponse(b *testing.B) {
+	resp := makeLargeGetBlocksFastResponse()
+	writer := &countingWriter{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		writer.buffer.Reset()
+		err := Write(writer, &resp)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.SetBytes(int64(writer.buffer.Len()))
+	}
+}